@@ -0,0 +1,247 @@
+// Package alerting evaluates threshold rules on the published events and tracks when alerts fire and resolve
+package alerting
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/hpcugent/gpfsbeat/config"
+)
+
+// Alert states
+const (
+	StatusAlert    = "alert"
+	StatusResolved = "resolved"
+)
+
+// rule is a validated alert rule
+type rule struct {
+	config.AlertRuleConfig
+	number    float64
+	isNumber  bool
+	condition func(v interface{}) (bool, bool) // returns whether the condition holds and whether it clears
+}
+
+// instanceState tracks a rule for a single instance, e.g., a single pool
+type instanceState struct {
+	rule         *rule
+	event        string
+	instance     common.MapStr
+	pendingSince time.Time
+	firing       bool
+	firedAt      time.Time
+	lastSeen     time.Time
+}
+
+// Engine evaluates the rules on every event it is shown, it is safe for concurrent use
+type Engine struct {
+	rules map[string][]*rule // by event key
+
+	mutex  sync.Mutex
+	states map[string]*instanceState
+}
+
+// toFloat converts the numeric values we find in events and configuration
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case bool:
+		return 0, false
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// newRule checks the rule configuration and builds its condition
+func newRule(c config.AlertRuleConfig) (*rule, error) {
+	if c.Name == "" || c.Event == "" || c.Field == "" {
+		return nil, fmt.Errorf("alert rules need a name, an event and a field")
+	}
+	r := &rule{AlertRuleConfig: c}
+	if r.Severity == "" {
+		r.Severity = "warning"
+	}
+	r.number, r.isNumber = toFloat(c.Value)
+	threshold := fmt.Sprint(c.Value)
+
+	switch c.Operator {
+	case "<", "<=", ">", ">=":
+		if !r.isNumber {
+			return nil, fmt.Errorf("alert rule %s compares with %s, which is not a number", c.Name, threshold)
+		}
+		r.condition = func(v interface{}) (bool, bool) {
+			f, ok := toFloat(v)
+			if !ok {
+				return false, false
+			}
+			switch c.Operator {
+			case "<":
+				return f < r.number, f >= r.number+c.Hysteresis
+			case "<=":
+				return f <= r.number, f > r.number+c.Hysteresis
+			case ">":
+				return f > r.number, f <= r.number-c.Hysteresis
+			default:
+				return f >= r.number, f < r.number-c.Hysteresis
+			}
+		}
+	case "==", "!=":
+		r.condition = func(v interface{}) (bool, bool) {
+			equal := fmt.Sprint(v) == threshold
+			if f, ok := toFloat(v); ok && r.isNumber {
+				equal = f == r.number
+			}
+			holds := equal == (c.Operator == "==")
+			return holds, !holds
+		}
+	default:
+		return nil, fmt.Errorf("alert rule %s has unknown operator %s", c.Name, c.Operator)
+	}
+	return r, nil
+}
+
+// New builds an engine for the configured rules
+func New(rules []config.AlertRuleConfig) (*Engine, error) {
+	e := &Engine{
+		rules:  make(map[string][]*rule),
+		states: make(map[string]*instanceState),
+	}
+	for _, c := range rules {
+		r, err := newRule(c)
+		if err != nil {
+			return nil, err
+		}
+		e.rules[c.Event] = append(e.rules[c.Event], r)
+	}
+	return e, nil
+}
+
+// matches returns true if the event has the values the rule is restricted to
+func (r *rule) matches(fields common.MapStr) bool {
+	for field, expected := range r.Match {
+		v, err := fields.GetValue(field)
+		if err != nil || fmt.Sprint(v) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// instance returns the values of the group_by fields, which identify what the rule is evaluated for
+func (r *rule) instance(fields common.MapStr) (string, common.MapStr) {
+	instance := common.MapStr{}
+	var parts []string
+	groupBy := append([]string{}, r.GroupBy...)
+	sort.Strings(groupBy)
+	for _, field := range groupBy {
+		v, _ := fields.GetValue(field)
+		instance[field] = v
+		parts = append(parts, fmt.Sprintf("%s=%v", field, v))
+	}
+	return strings.Join(parts, ","), instance
+}
+
+// alertMapStr describes a state change of a rule for an instance
+func (r *rule) alertMapStr(status string, event string, instance common.MapStr, value interface{}, since time.Time) common.MapStr {
+	return common.MapStr{
+		"status":    status,
+		"rule":      r.Name,
+		"severity":  r.Severity,
+		"event":     event,
+		"field":     r.Field,
+		"operator":  r.Operator,
+		"threshold": r.Value,
+		"value":     value,
+		"instance":  instance,
+		"since":     since,
+	}
+}
+
+// Evaluate checks the rules for the event published under the given key and returns the alert and resolved
+// events that should be published
+func (e *Engine) Evaluate(event string, fields common.MapStr, now time.Time) []common.MapStr {
+	rules := e.rules[event]
+	if len(rules) == 0 {
+		return nil
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var alerts []common.MapStr
+	for _, r := range rules {
+		if !r.matches(fields) {
+			continue
+		}
+		value, err := fields.GetValue(r.Field)
+		if err != nil {
+			continue
+		}
+		holds, clears := r.condition(value)
+
+		id, instance := r.instance(fields)
+		key := r.Name + "|" + id
+		s, ok := e.states[key]
+		if !ok {
+			if !holds {
+				continue // nothing to keep track of
+			}
+			s = &instanceState{rule: r, event: event, instance: instance}
+			e.states[key] = s
+		}
+		s.lastSeen = now
+
+		switch {
+		case !s.firing && holds:
+			if s.pendingSince.IsZero() {
+				s.pendingSince = now
+			}
+			if now.Sub(s.pendingSince) >= r.For {
+				s.firing = true
+				s.firedAt = now
+				alerts = append(alerts, r.alertMapStr(StatusAlert, event, instance, value, s.pendingSince))
+			}
+		case !s.firing:
+			delete(e.states, key)
+		case clears:
+			alerts = append(alerts, r.alertMapStr(StatusResolved, event, instance, value, s.firedAt))
+			delete(e.states, key)
+		}
+	}
+	return alerts
+}
+
+// Expire forgets the instances that were not evaluated since the given time, e.g., a user that no longer has any
+// quota, and returns the resolved events for those that were firing
+func (e *Engine) Expire(before time.Time) []common.MapStr {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var alerts []common.MapStr
+	for key, s := range e.states {
+		if !s.lastSeen.Before(before) {
+			continue
+		}
+		if s.firing {
+			alert := s.rule.alertMapStr(StatusResolved, s.event, s.instance, nil, s.firedAt)
+			alert["expired"] = true
+			alerts = append(alerts, alert)
+		}
+		delete(e.states, key)
+	}
+	return alerts
+}
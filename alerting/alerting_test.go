@@ -0,0 +1,133 @@
+//go:build !integration
+
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/hpcugent/gpfsbeat/config"
+)
+
+func poolInfo(pool string, free float64) common.MapStr {
+	return common.MapStr{"device": "fs1", "pool_name": pool, "free_percentage": free}
+}
+
+// statuses returns the status of each alert
+func statuses(alerts []common.MapStr) []string {
+	var s []string
+	for _, a := range alerts {
+		s = append(s, a["status"].(string))
+	}
+	return s
+}
+
+func expectStatuses(t *testing.T, step string, alerts []common.MapStr, expected ...string) {
+	t.Helper()
+	got := statuses(alerts)
+	if len(got) != len(expected) {
+		t.Fatalf("%s: expected %q, got %q", step, expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("%s: expected %q, got %q", step, expected, got)
+		}
+	}
+}
+
+func TestHysteresis(t *testing.T) {
+	e, err := New([]config.AlertRuleConfig{{
+		Name: "pool_full", Event: "mmdf", Field: "free_percentage", Operator: "<", Value: 10,
+		GroupBy: []string{"device", "pool_name"}, Hysteresis: 5,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	expectStatuses(t, "above the threshold", e.Evaluate("mmdf", poolInfo("data", 20), now))
+	alerts := e.Evaluate("mmdf", poolInfo("data", 8), now)
+	expectStatuses(t, "below the threshold", alerts, StatusAlert)
+	if alerts[0]["value"] != 8.0 || alerts[0]["instance"].(common.MapStr)["pool_name"] != "data" {
+		t.Errorf("unexpected alert %v", map[string]interface{}(alerts[0]))
+	}
+	expectStatuses(t, "still below", e.Evaluate("mmdf", poolInfo("data", 9), now))
+	expectStatuses(t, "within the hysteresis", e.Evaluate("mmdf", poolInfo("data", 12), now))
+	expectStatuses(t, "other instance", e.Evaluate("mmdf", poolInfo("system", 50), now))
+	expectStatuses(t, "past the hysteresis", e.Evaluate("mmdf", poolInfo("data", 15), now), StatusResolved)
+	expectStatuses(t, "other event", e.Evaluate("quota", poolInfo("data", 1), now))
+}
+
+func TestFor(t *testing.T) {
+	e, err := New([]config.AlertRuleConfig{{
+		Name: "quota_over_hard", Event: "quota", Field: "state", Operator: "==", Value: "over_hard",
+		Match: map[string]string{"kind": "USR"}, GroupBy: []string{"entity.id"}, For: 10 * time.Minute,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	quota := func(kind string, state string) common.MapStr {
+		return common.MapStr{"kind": kind, "entity": common.MapStr{"id": "1000"}, "state": state}
+	}
+	start := time.Now()
+
+	expectStatuses(t, "pending", e.Evaluate("quota", quota("USR", "over_hard"), start))
+	expectStatuses(t, "still pending", e.Evaluate("quota", quota("USR", "over_hard"), start.Add(5*time.Minute)))
+	expectStatuses(t, "interrupted", e.Evaluate("quota", quota("USR", "ok"), start.Add(6*time.Minute)))
+	expectStatuses(t, "pending again", e.Evaluate("quota", quota("USR", "over_hard"), start.Add(7*time.Minute)))
+	expectStatuses(t, "not long enough", e.Evaluate("quota", quota("USR", "over_hard"), start.Add(16*time.Minute)))
+	alerts := e.Evaluate("quota", quota("USR", "over_hard"), start.Add(17*time.Minute))
+	expectStatuses(t, "long enough", alerts, StatusAlert)
+	if alerts[0]["since"] != start.Add(7*time.Minute) {
+		t.Errorf("expected the alert to be pending since the condition holds again, got %v", alerts[0]["since"])
+	}
+	expectStatuses(t, "no match", e.Evaluate("quota", quota("GRP", "ok"), start.Add(18*time.Minute)))
+	expectStatuses(t, "resolved", e.Evaluate("quota", quota("USR", "ok"), start.Add(19*time.Minute)), StatusResolved)
+}
+
+func TestExpire(t *testing.T) {
+	e, err := New([]config.AlertRuleConfig{{
+		Name: "pool_full", Event: "mmdf", Field: "free_percentage", Operator: "<", Value: 10,
+		GroupBy: []string{"pool_name"}, For: time.Hour,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+
+	e.Evaluate("mmdf", poolInfo("ok", 50), start)
+	if len(e.states) != 0 {
+		t.Errorf("expected no state for an instance that never met the condition, got %d", len(e.states))
+	}
+	e.Evaluate("mmdf", poolInfo("pending", 5), start)
+	e.Evaluate("mmdf", poolInfo("firing", 5), start)
+	expectStatuses(t, "firing", e.Evaluate("mmdf", poolInfo("firing", 5), start.Add(time.Hour)), StatusAlert)
+	e.Evaluate("mmdf", poolInfo("pending", 50), start.Add(time.Hour))
+	if len(e.states) != 1 {
+		t.Errorf("expected only the firing instance to be kept, got %d", len(e.states))
+	}
+
+	e.Evaluate("mmdf", poolInfo("pending", 5), start.Add(2*time.Hour))
+	expectStatuses(t, "recently seen", e.Expire(start.Add(time.Hour)))
+	alerts := e.Expire(start.Add(3 * time.Hour))
+	expectStatuses(t, "gone", alerts, StatusResolved)
+	if alerts[0]["expired"] != true || alerts[0]["instance"].(common.MapStr)["pool_name"] != "firing" {
+		t.Errorf("unexpected expiry %v", map[string]interface{}(alerts[0]))
+	}
+	if len(e.states) != 0 {
+		t.Errorf("expected all states to be expired, got %d", len(e.states))
+	}
+}
+
+func TestNewRule(t *testing.T) {
+	for _, c := range []config.AlertRuleConfig{
+		{Event: "mmdf", Field: "free_percentage", Operator: "<", Value: 10},
+		{Name: "r", Event: "mmdf", Field: "free_percentage", Operator: "<", Value: "ten"},
+		{Name: "r", Event: "mmdf", Field: "free_percentage", Operator: "~", Value: 10},
+	} {
+		if _, err := New([]config.AlertRuleConfig{c}); err == nil {
+			t.Errorf("expected an error for %+v", c)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package beater
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+var auditPollInterval = 10 * time.Second
+
+// auditRegistryPrefix prefixes the keys of the audit file offsets in the registry
+const auditRegistryPrefix = "audit::"
+
+// auditFileState is what we keep in the registry for each audit log file. The device and inode tell us whether the
+// path still points to the same file after a restart.
+type auditFileState struct {
+	Offset int64  `json:"offset"`
+	Device uint64 `json:"device"`
+	Inode  uint64 `json:"inode"`
+}
+
+// sameFile returns true if the state belongs to the file, or if the state predates keeping the file identity
+func (s auditFileState) sameFile(info os.FileInfo) bool {
+	if s.Device == 0 && s.Inode == 0 {
+		return true
+	}
+	device, inode := fileIdentity(info)
+	return s.Device == device && s.Inode == inode
+}
+
+// auditOffset is attached to each published event, so we can update the registry once the output acknowledged it
+type auditOffset struct {
+	path  string
+	state auditFileState
+}
+
+// auditConsumer reads the audit log files, and remembers how far it read in each of them
+type auditConsumer struct {
+	bt     *gpfsbeat
+	b      *beat.Beat
+	dirs   []string
+	client beat.Client
+	store  *statestore.Store
+
+	// offsets holds how far we read, which may be ahead of what is acknowledged and stored in the registry
+	offsets map[string]int64
+	// files holds the file we read at each path, to notice when a new file replaces it
+	files map[string]os.FileInfo
+}
+
+// auditLogFiles returns the audit log files found under the given directories, together with the directories that
+// could not be walked completely (e.g., because the filesystem is not mounted)
+func auditLogFiles(dirs []string) ([]string, map[string]bool) {
+	var files []string
+	failed := make(map[string]bool)
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				logp.Warn("Cannot read %s in audit directory %s. Error: %s", path, dir, err)
+				failed[dir] = true
+				return nil // e.g., a directory that was removed by the retention policy
+			}
+			if info.Mode().IsRegular() && strings.HasPrefix(info.Name(), "auditLogFile") && !strings.HasSuffix(info.Name(), ".gz") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			logp.Err("Cannot walk audit directory %s. Error: %s", dir, err)
+			failed[dir] = true
+		}
+	}
+	return files, failed
+}
+
+// removed returns true if the file is really gone, and not just missing because its directory could not be walked
+func (c *auditConsumer) removed(path string, failed map[string]bool) bool {
+	for _, dir := range c.dirs {
+		if failed[dir] && strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/") {
+			return false
+		}
+	}
+	_, err := os.Stat(path)
+	return os.IsNotExist(err)
+}
+
+// ack stores the offsets of the acknowledged events in the registry
+func (c *auditConsumer) ack(acked int, data []interface{}) {
+	states := make(map[string]auditFileState)
+	for _, d := range data {
+		// a replaced file restarts at a lower offset, so the last acknowledged event wins
+		if o, ok := d.(auditOffset); ok {
+			states[o.path] = o.state
+		}
+	}
+	for path, state := range states {
+		if err := c.store.Set(auditRegistryPrefix+path, state); err != nil {
+			logp.Err("Cannot update the registry entry for %s. Error: %s", path, err)
+		}
+	}
+}
+
+// offset returns how far we already read the file, falling back to the registry after a restart. The offset is reset
+// when a new file replaced the one we read.
+func (c *auditConsumer) offset(path string, info os.FileInfo) int64 {
+	if offset, ok := c.offsets[path]; ok {
+		if previous, ok := c.files[path]; ok && !os.SameFile(previous, info) {
+			logp.Warn("Audit file %s was replaced, starting from the beginning", path)
+			return 0
+		}
+		return offset
+	}
+	var state auditFileState
+	key := auditRegistryPrefix + path
+	if ok, _ := c.store.Has(key); ok {
+		if err := c.store.Get(key, &state); err != nil {
+			logp.Err("Cannot read the registry entry for %s. Error: %s", path, err)
+		}
+	}
+	if !state.sameFile(info) {
+		logp.Warn("Audit file %s was replaced, starting from the beginning", path)
+		return 0
+	}
+	return state.Offset
+}
+
+// consumeFile publishes the records appended to the file since we last read it and returns the new offset
+func (c *auditConsumer) consumeFile(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	offset := c.offset(path, info)
+	c.files[path] = info
+	if info.Size() < offset {
+		logp.Warn("Audit file %s shrunk, starting from the beginning", path)
+		offset = 0
+	}
+	if info.Size() == offset {
+		return offset, nil
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	device, inode := fileIdentity(info)
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return offset, nil // incomplete line, we pick it up during the next poll
+		}
+		offset += int64(len(line))
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		record, err := parser.ParseAuditRecord(line)
+		if err != nil {
+			logp.Err("Cannot parse audit record in %s before offset %d. Error: %s", path, offset, err)
+			continue
+		}
+		info := record.ToMapStr()
+		event := beat.Event{
+			Timestamp: record.EventTime(),
+			Fields: common.MapStr{
+				"type":  c.b.Info.Name,
+				"audit": info,
+			},
+			Private: auditOffset{path: path, state: auditFileState{Offset: offset, Device: device, Inode: inode}},
+		}
+		c.client.Publish(event)
+		c.bt.evaluateAlerts(c.b, "audit", info)
+	}
+}
+
+// poll consumes all audit files once
+func (c *auditConsumer) poll() {
+	files, failed := auditLogFiles(c.dirs)
+	seen := make(map[string]bool)
+	for _, path := range files {
+		seen[path] = true
+
+		offset, err := c.consumeFile(path)
+		if err != nil {
+			logp.Err("Cannot read audit file %s. Error: %s", path, err)
+			continue
+		}
+		c.offsets[path] = offset
+	}
+
+	// forget about the files that were removed, e.g., compressed after rotation
+	for path := range c.offsets {
+		if !seen[path] && c.removed(path, failed) {
+			delete(c.offsets, path)
+			delete(c.files, path)
+		}
+	}
+	var gone []string
+	err := c.store.Each(func(key string, _ statestore.ValueDecoder) (bool, error) {
+		if path := strings.TrimPrefix(key, auditRegistryPrefix); path != key && !seen[path] && c.removed(path, failed) {
+			gone = append(gone, key)
+		}
+		return true, nil
+	})
+	if err != nil {
+		logp.Err("Cannot list the audit entries in the registry. Error: %s", err)
+	}
+	for _, key := range gone {
+		if err := c.store.Remove(key); err != nil {
+			logp.Err("Cannot remove the registry entry %s. Error: %s", key, err)
+		}
+	}
+}
+
+// consumeAuditLogs polls the audit log directories until the beat is stopped
+func (bt *gpfsbeat) consumeAuditLogs(b *beat.Beat) {
+	store, err := bt.stateStore()
+	if err != nil {
+		logp.Err("Not consuming audit logs without a registry")
+		return
+	}
+
+	c := &auditConsumer{
+		bt:      bt,
+		b:       b,
+		dirs:    bt.config.AuditPaths,
+		store:   store,
+		offsets: make(map[string]int64),
+		files:   make(map[string]os.FileInfo),
+	}
+	c.client, err = b.Publisher.ConnectWith(beat.ClientConfig{
+		ACKHandler: acker.ConnectionOnly(acker.EventPrivateReporter(c.ack)),
+	})
+	if err != nil {
+		logp.Err("Cannot connect the audit log consumer to the publisher. Error: %s", err)
+		return
+	}
+	defer c.client.Close()
+
+	ticker := time.NewTicker(auditPollInterval)
+	defer ticker.Stop()
+	for {
+		c.poll()
+
+		select {
+		case <-bt.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,172 @@
+//go:build !integration
+
+package beater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+)
+
+// testClient collects the published events
+type testClient struct {
+	events []beat.Event
+}
+
+func (c *testClient) Publish(event beat.Event)       { c.events = append(c.events, event) }
+func (c *testClient) PublishAll(events []beat.Event) { c.events = append(c.events, events...) }
+func (c *testClient) Close() error                   { return nil }
+
+const auditTestRecord = `{"LWE_JSON": "0.0.2", "path": "/gpfs/fs1/data/file", "clusterName": "cluster", "nodeName": "node1", "fsName": "fs1", "event": "CREATE", "inode": "123", "fileSize": "0", "ownerUserId": "1000", "ownerGroupId": "1000", "processId": "42", "permissions": "200100644", "eventTime": "2026-10-19_10:00:00+0200", "clientUserId": "1000", "clientGroupId": "1000", "nfsClientIp": "", "filesetName": "data", "poolName": "system"}` + "\n"
+
+func newTestAuditConsumer(store *statestore.Store, dirs ...string) (*auditConsumer, *testClient) {
+	client := &testClient{}
+	return &auditConsumer{
+		bt:      &gpfsbeat{},
+		b:       &beat.Beat{Info: beat.Info{Name: "gpfsbeat"}},
+		dirs:    dirs,
+		client:  client,
+		store:   store,
+		offsets: make(map[string]int64),
+		files:   make(map[string]os.FileInfo),
+	}, client
+}
+
+// ackAll acknowledges all events published so far
+func ackAll(c *auditConsumer, client *testClient) {
+	data := make([]interface{}, 0, len(client.events))
+	for _, e := range client.events {
+		data = append(data, e.Private)
+	}
+	c.ack(len(data), data)
+}
+
+// storedAuditState returns the registry entry of the audit file
+func storedAuditState(t *testing.T, store *statestore.Store, path string) auditFileState {
+	t.Helper()
+	var state auditFileState
+	if err := store.Get(auditRegistryPrefix+path, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func fileInfo(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestAuditOffsets(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, ".audit_log")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "auditLogFile.2026-10-19_10.00.00")
+	appendLog(t, path, auditTestRecord+auditTestRecord+"{\"event\": \"incomplete")
+
+	store := newTestStore(t)
+	c, client := newTestAuditConsumer(store, dir)
+	c.poll()
+	if len(client.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(client.events))
+	}
+	ackAll(c, client)
+	if state := storedAuditState(t, store, path); state.Offset != int64(2*len(auditTestRecord)) || !state.sameFile(fileInfo(t, path)) {
+		t.Errorf("expected offset %d of %s, got %+v", 2*len(auditTestRecord), path, state)
+	}
+
+	// after a restart, we continue from the acknowledged offset, including the completed line
+	c, client = newTestAuditConsumer(store, dir)
+	appendLog(t, path, "\", \"eventTime\": \"2026-10-19_10:00:01+0200\"}\n")
+	c.poll()
+	if len(client.events) != 1 {
+		t.Fatalf("expected 1 event after the restart, got %d", len(client.events))
+	}
+	ackAll(c, client)
+
+	// the offset survives a poll during which the directory is unavailable
+	hidden := filepath.Join(root, "hidden")
+	if err := os.Rename(dir, hidden); err != nil {
+		t.Fatal(err)
+	}
+	c, client = newTestAuditConsumer(store, dir)
+	c.poll()
+	if ok, _ := store.Has(auditRegistryPrefix + path); !ok {
+		t.Fatal("offset forgotten while the audit directory was unavailable")
+	}
+	if err := os.Rename(hidden, dir); err != nil {
+		t.Fatal(err)
+	}
+	c.poll()
+	if len(client.events) != 0 {
+		t.Errorf("expected no events when the directory is back, got %d", len(client.events))
+	}
+
+	// once the file is rotated, its offset is forgotten
+	if err := os.Rename(path, path+".gz"); err != nil {
+		t.Fatal(err)
+	}
+	c.poll()
+	if ok, _ := store.Has(auditRegistryPrefix + path); ok {
+		t.Error("offset kept for a rotated file")
+	}
+	if _, ok := c.offsets[path]; ok {
+		t.Error("in-memory offset kept for a rotated file")
+	}
+}
+
+func TestAuditReplacedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "auditLogFile.2026-10-19_10.00.00")
+	appendLog(t, path, auditTestRecord+auditTestRecord)
+
+	store := newTestStore(t)
+	c, client := newTestAuditConsumer(store, dir)
+	c.poll()
+	ackAll(c, client)
+
+	// after a restart, the unchanged file is not read again
+	c, client = newTestAuditConsumer(store, dir)
+	c.poll()
+	if len(client.events) != 0 {
+		t.Fatalf("expected no duplicates after a restart, got %d events", len(client.events))
+	}
+
+	// a new, larger file at the same path is read from the start, also when it shows up during a restart
+	replace := func(records int) {
+		t.Helper()
+		if err := os.Rename(path, path+".old"); err != nil {
+			t.Fatal(err)
+		}
+		appendLog(t, path, strings.Repeat(auditTestRecord, records))
+		if err := os.Remove(path + ".old"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replace(3)
+	c.poll()
+	if len(client.events) != 3 {
+		t.Fatalf("expected the 3 records of the new file, got %d events", len(client.events))
+	}
+	ackAll(c, client)
+
+	replace(4)
+	c, client = newTestAuditConsumer(store, dir)
+	c.poll()
+	if len(client.events) != 4 {
+		t.Fatalf("expected the 4 records of the file replaced during the restart, got %d events", len(client.events))
+	}
+	ackAll(c, client)
+	if state := storedAuditState(t, store, path); state.Offset != int64(4*len(auditTestRecord)) || !state.sameFile(fileInfo(t, path)) {
+		t.Errorf("expected the offset of the new file, got %+v", state)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package beater
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode of the file, so we can recognise it after a restart, when we no longer
+// have the os.FileInfo to pass to os.SameFile
+func fileIdentity(info os.FileInfo) (uint64, uint64) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev), uint64(st.Ino)
+	}
+	return 0, 0
+}
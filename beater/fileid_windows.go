@@ -0,0 +1,8 @@
+package beater
+
+import "os"
+
+// fileIdentity is not available on Windows, so we only go by the file size there
+func fileIdentity(info os.FileInfo) (uint64, uint64) {
+	return 0, 0
+}
@@ -0,0 +1,103 @@
+package beater
+
+import (
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/hpcugent/gpfsbeat/config"
+	"github.com/hpcugent/gpfsbeat/forecast"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+// forecastRegistryPrefix prefixes the keys of the usage windows in the registry
+const forecastRegistryPrefix = "forecast::"
+
+// capacityUsages collects the usage of the filesets, pools and filesystems from the collected information
+func capacityUsages(quotas []parser.QuotaInfo, mmdfinfos []parser.ParseResult) []parser.CapacityUsage {
+	var usages []parser.CapacityUsage
+	for i := range quotas {
+		if u, ok := quotas[i].CapacityUsage(); ok {
+			usages = append(usages, u)
+		}
+	}
+	for _, info := range mmdfinfos {
+		switch i := info.(type) {
+		case *parser.MmDfPoolTotalInfo:
+			usages = append(usages, i.CapacityUsage())
+		case *parser.MmDfFsTotalInfo:
+			usages = append(usages, i.CapacityUsage())
+		}
+	}
+	return usages
+}
+
+// forecastMapStr describes the fitted fill rate and when the limits will be reached
+func forecastMapStr(u parser.CapacityUsage, w *forecast.Window, fit forecast.Fit) common.MapStr {
+	m := common.MapStr{
+		"kind":                    u.Kind,
+		"filesystem":              u.Filesystem,
+		"name":                    u.Name,
+		"used":                    u.Used,
+		"samples":                 len(w.Samples),
+		"window_start":            w.Samples[0].Time,
+		"fill_rate_bytes_per_day": fit.RatePerDay(),
+		"confidence":              fit.RSquared,
+	}
+	for _, l := range []struct {
+		name  string
+		limit int64
+	}{
+		{"soft", u.Soft},
+		{"hard", u.Hard},
+		{"full", u.Capacity},
+	} {
+		if d, ok := fit.Until(float64(l.limit)); ok {
+			m[l.name] = common.MapStr{
+				"limit":        l.limit,
+				"seconds_left": int64(d.Seconds()),
+				"estimated_at": fit.Last.Add(d),
+			}
+		}
+	}
+	return m
+}
+
+// forecastCapacity adds the current usage to the windows kept in the registry and returns the forecasts
+func forecastCapacity(store *statestore.Store, c config.ForecastConfig, usages []parser.CapacityUsage, now time.Time) []common.MapStr {
+	minInterval := c.Window / time.Duration(c.MaxSamples)
+
+	var forecasts []common.MapStr
+	for _, u := range usages {
+		key := forecastRegistryPrefix + u.ID()
+
+		var w forecast.Window
+		known, err := store.Has(key)
+		if err == nil && known {
+			err = store.Get(key, &w)
+		}
+		if err != nil {
+			logp.Err("Cannot read the usage window for %s. Error: %s", u.ID(), err)
+			continue
+		}
+
+		// once the window is full, a new sample replaces the oldest one, so we cannot go by the number of samples
+		added := w.Add(forecast.Sample{Time: now, Used: float64(u.Used)}, c.Window, minInterval)
+		if added || !known {
+			if err := store.Set(key, w); err != nil {
+				logp.Err("Cannot store the usage window for %s. Error: %s", u.ID(), err)
+			}
+		}
+
+		if len(w.Samples) < c.MinSamples {
+			continue
+		}
+		fit, ok := w.Fit()
+		if !ok {
+			continue
+		}
+		forecasts = append(forecasts, forecastMapStr(u, &w, fit))
+	}
+	return forecasts
+}
@@ -0,0 +1,72 @@
+//go:build !integration
+
+package beater
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend/memlog"
+	"github.com/hpcugent/gpfsbeat/config"
+	"github.com/hpcugent/gpfsbeat/forecast"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+// newTestStore returns an empty registry store in a temporary directory
+func newTestStore(t *testing.T) *statestore.Store {
+	t.Helper()
+	backend, err := memlog.New(logp.NewLogger("test"), memlog.Settings{Root: t.TempDir(), FileMode: 0600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := statestore.NewRegistry(backend).Get("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestForecastCapacity(t *testing.T) {
+	store := newTestStore(t)
+	c := config.ForecastConfig{Window: 4 * time.Hour, MinSamples: 3, MaxSamples: 4}
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	usage := func(used int64) []parser.CapacityUsage {
+		return []parser.CapacityUsage{{Kind: parser.CapacityFileset, Filesystem: "fs1", Name: "data", Used: used, Hard: 100000}}
+	}
+
+	for i := 0; i < 2; i++ {
+		if f := forecastCapacity(store, c, usage(int64(1000*i)), start.Add(time.Duration(i)*time.Hour)); len(f) != 0 {
+			t.Fatalf("expected no forecast before the minimum number of samples, got %d", len(f))
+		}
+	}
+
+	// the window keeps moving once it is full, so the fit follows the recent usage
+	var forecasts []map[string]interface{}
+	for i := 2; i < 10; i++ {
+		used := int64(1000 * i)
+		if i > 5 {
+			used = 5000 + 2000*int64(i-5)
+		}
+		for _, f := range forecastCapacity(store, c, usage(used), start.Add(time.Duration(i)*time.Hour)) {
+			forecasts = append(forecasts, f)
+		}
+	}
+	if len(forecasts) != 8 {
+		t.Fatalf("expected a forecast for every run, got %d", len(forecasts))
+	}
+	last := forecasts[len(forecasts)-1]
+	if last["samples"] != 5 || last["window_start"] != start.Add(5*time.Hour) {
+		t.Errorf("expected the window to move, got %v samples starting at %v", last["samples"], last["window_start"])
+	}
+	if rate := last["fill_rate_bytes_per_day"].(float64); math.Abs(rate-2000*24) > 1e-6 {
+		t.Errorf("expected the fit to follow the faster recent growth, got %f per day", rate)
+	}
+
+	var w forecast.Window
+	if err := store.Get(forecastRegistryPrefix+usage(0)[0].ID(), &w); err != nil || len(w.Samples) != 5 {
+		t.Errorf("expected the moving window in the registry, got %d samples (%v)", len(w.Samples), err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"os/exec"
+	"strconv"
 	"time"
 
 	"github.com/elastic/beats/v7/libbeat/logp"
@@ -15,6 +16,9 @@ var mmrepquotaTimeOut = 5 * 60 * 1000 * time.Millisecond
 var mmlsfsTimeout = 1 * 60 * 1000 * time.Millisecond
 var mmdfTimeout = 5 * 60 * 1000 * time.Millisecond
 var mmlsfilesetTimeout = 5 * 60 * 1000 * time.Millisecond
+var mmvdiskTimeout = 2 * 60 * 1000 * time.Millisecond
+var mmlsrecoverygroupTimeout = 2 * 60 * 1000 * time.Millisecond
+var mmlsqosTimeout = 2 * 60 * 1000 * time.Millisecond
 
 // MmLsFs returns an array of the devices known to the GPFS cluster
 func (bt *gpfsbeat) MmLsFs() ([]string, error) {
@@ -41,29 +45,50 @@ func (bt *gpfsbeat) MmLsFs() ([]string, error) {
 	return devices, nil
 }
 
-// MmRepQuota is a wrapper around the mmrepquota command
-func (bt *gpfsbeat) MmRepQuota() ([]parser.QuotaInfo, error) {
-	var quotas []parser.QuotaInfo
+// MmLsFsAttributes returns the block size and subblock size of each device
+func (bt *gpfsbeat) MmLsFsAttributes() (map[string]parser.MmLsFsAttributes, error) {
+	attributes := make(map[string]parser.MmLsFsAttributes)
 
 	for _, device := range bt.config.Devices {
-
-		logp.Info("Running mmrepquota for device %s", device)
-
-		ctx, cancel := context.WithTimeout(context.Background(), mmrepquotaTimeOut)
+		ctx, cancel := context.WithTimeout(context.Background(), mmlsfsTimeout)
 		defer cancel()
 
-		cmd := exec.CommandContext(ctx, bt.config.MMRepQuotaCommand, "-Y", device)
+		cmd := exec.CommandContext(ctx, bt.config.MMLsFsCommand, device, "-B", "-f", "-Y")
 		var out bytes.Buffer
 		cmd.Stdout = &out
 
 		err := cmd.Run()
 		if err != nil {
-			logp.Err("Command mmrepquota did not run correctly for device %s! Aborting. Error: %s", device, err)
-			return nil, errors.New("mmrepquota failed")
+			logp.Err("Command %s did not run correctly for device %s! Error: %s", bt.config.MMLsFsCommand, device, err)
+			return nil, errors.New("mmlsfs failed")
+		}
+
+		as, err := parser.ParseMmLsFsAttributes(out.String())
+		if err != nil {
+			return nil, errors.New("mmlsfs attributes could not be parsed")
+		}
+		for d, a := range as {
+			attributes[d] = a
+		}
+	}
+	return attributes, nil
+}
+
+// MmRepQuota is a wrapper around the mmrepquota command
+func (bt *gpfsbeat) MmRepQuota() ([]parser.QuotaInfo, error) {
+	var quotas []parser.QuotaInfo
+
+	for _, device := range bt.config.Devices {
+
+		logp.Info("Running mmrepquota for device %s", device)
+
+		out, err := bt.runMmRepQuota(device)
+		if err != nil {
+			return nil, err
 		}
 
 		var qs []parser.QuotaInfo
-		qs, err = parser.ParseMmRepQuota(out.String())
+		qs, err = parser.ParseMmRepQuota(out)
 		if err != nil {
 			return nil, errors.New("mmrepquota info could not be parsed")
 		}
@@ -72,6 +97,51 @@ func (bt *gpfsbeat) MmRepQuota() ([]parser.QuotaInfo, error) {
 	return quotas, nil
 }
 
+// runMmRepQuota runs mmrepquota with the given extra flags for the device
+func (bt *gpfsbeat) runMmRepQuota(device string, flags ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mmrepquotaTimeOut)
+	defer cancel()
+
+	args := append(append([]string{}, flags...), "-Y", device)
+	cmd := exec.CommandContext(ctx, bt.config.MMRepQuotaCommand, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		logp.Err("Command mmrepquota %q did not run correctly for device %s! Error: %s", flags, device, err)
+		return "", errors.New("mmrepquota failed")
+	}
+	return out.String(), nil
+}
+
+// MmRepQuotaDefaults gathers the default quota limits and grace periods through mmrepquota -d and -t
+func (bt *gpfsbeat) MmRepQuotaDefaults() ([]parser.QuotaDefaultsInfo, error) {
+	var defaults []parser.QuotaDefaultsInfo
+
+	for _, device := range bt.config.Devices {
+
+		logp.Info("Running mmrepquota -d and -t for device %s", device)
+
+		defaultsOutput, err := bt.runMmRepQuota(device, "-d")
+		if err != nil {
+			return nil, err
+		}
+		graceOutput, err := bt.runMmRepQuota(device, "-t")
+		if err != nil {
+			return nil, err
+		}
+
+		var ds []parser.QuotaDefaultsInfo
+		ds, err = parser.ParseMmRepQuotaDefaults(device, defaultsOutput, graceOutput)
+		if err != nil {
+			return nil, errors.New("mmrepquota defaults info could not be parsed")
+		}
+		defaults = append(defaults, ds...)
+	}
+	return defaults, nil
+}
+
 // MmDf is a wrapper around the mmdf command
 func (bt *gpfsbeat) MmDf() ([]parser.ParseResult, error) {
 
@@ -115,7 +185,11 @@ func (bt *gpfsbeat) MmLsFileset() ([]parser.MmLsFilesetInfo, error) {
 		ctx, cancel := context.WithTimeout(context.Background(), mmlsfilesetTimeout)
 		defer cancel()
 
-		cmd := exec.CommandContext(ctx, bt.config.MMLsFilesetCommand, device, "-L", "-Y")
+		args := []string{device, "-L", "-Y"}
+		if bt.config.MMLsFilesetAFM {
+			args = []string{device, "-L", "--afm", "-Y"}
+		}
+		cmd := exec.CommandContext(ctx, bt.config.MMLsFilesetCommand, args...)
 		var out bytes.Buffer
 		cmd.Stdout = &out
 
@@ -135,3 +209,108 @@ func (bt *gpfsbeat) MmLsFileset() ([]parser.MmLsFilesetInfo, error) {
 
 	return mmlsfilesetinfos, nil
 }
+
+// runMmVdisk runs mmvdisk with the given arguments and parses the output
+func (bt *gpfsbeat) runMmVdisk(args ...string) ([]parser.ParseResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mmvdiskTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, bt.config.MMVdiskCommand, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if err != nil {
+		logp.Err("Command mmvdisk %s did not run correctly! Error: %s", args, err)
+		return nil, errors.New("mmvdisk failed")
+	}
+
+	infos, err := parser.ParseMmVdisk(out.String())
+	if err != nil {
+		return nil, errors.New("mmvdisk info could not be parsed")
+	}
+	return infos, nil
+}
+
+// GNR gathers the recovery group, declustered array and non-ok pdisk information on ESS/GNR clusters
+func (bt *gpfsbeat) GNR() ([]parser.ParseResult, error) {
+
+	logp.Info("Running mmvdisk recoverygroup list")
+	rgs, err := bt.runMmVdisk("recoverygroup", "list", "-Y")
+	if err != nil {
+		return nil, err
+	}
+
+	logp.Info("Running mmvdisk pdisk list")
+	pdisks, err := bt.runMmVdisk("pdisk", "list", "--recovery-group", "all", "--not-ok", "-Y")
+	if err != nil {
+		return nil, err
+	}
+
+	var gnrinfos []parser.ParseResult
+	gnrinfos = append(gnrinfos, rgs...)
+	gnrinfos = append(gnrinfos, pdisks...)
+
+	for _, r := range rgs {
+		rg, ok := r.(*parser.MmVdiskRecoveryGroupInfo)
+		if !ok {
+			continue
+		}
+		name := rg.Name()
+
+		logp.Info("Running mmlsrecoverygroup for recovery group %s", name)
+
+		ctx, cancel := context.WithTimeout(context.Background(), mmlsrecoverygroupTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, bt.config.MMLsRecoveryGroupCommand, name, "-L", "-Y")
+		var out bytes.Buffer
+		cmd.Stdout = &out
+
+		err := cmd.Run()
+		if err != nil {
+			logp.Err("Command mmlsrecoverygroup did not run correctly for recovery group %s! Error: %s", name, err)
+			return nil, errors.New("mmlsrecoverygroup failed")
+		}
+
+		var das []parser.ParseResult
+		das, err = parser.ParseMmLsRecoveryGroup(out.String())
+		if err != nil {
+			return nil, errors.New("mmlsrecoverygroup info could not be parsed")
+		}
+		gnrinfos = append(gnrinfos, das...)
+	}
+
+	return gnrinfos, nil
+}
+
+// MmLsQos is a wrapper around the mmlsqos command
+func (bt *gpfsbeat) MmLsQos() ([]parser.ParseResult, error) {
+
+	var qosinfos []parser.ParseResult
+
+	for _, device := range bt.config.Devices {
+		logp.Info("Running mmlsqos for device %s", device)
+
+		ctx, cancel := context.WithTimeout(context.Background(), mmlsqosTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, bt.config.MMLsQosCommand, device, "-Y", "--seconds", strconv.Itoa(bt.config.QoSSeconds))
+		var out bytes.Buffer
+		cmd.Stdout = &out
+
+		err := cmd.Run()
+		if err != nil {
+			logp.Err("Command mmlsqos did not run correctly for device %s! Aborting. Error: %s", device, err)
+			return nil, errors.New("mmlsqos failed")
+		}
+
+		var qs []parser.ParseResult
+		qs, err = parser.ParseMmLsQos(device, out.String())
+		if err != nil {
+			return nil, errors.New("mmlsqos info could not be parsed")
+		}
+		qosinfos = append(qosinfos, qs...)
+	}
+	return qosinfos, nil
+}
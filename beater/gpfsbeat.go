@@ -3,20 +3,45 @@ package beater
 import (
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
 
+	"github.com/hpcugent/gpfsbeat/alerting"
 	"github.com/hpcugent/gpfsbeat/config"
+	"github.com/hpcugent/gpfsbeat/metadata"
+	"github.com/hpcugent/gpfsbeat/notify"
+	"github.com/hpcugent/gpfsbeat/parser"
+	"github.com/hpcugent/gpfsbeat/resolver"
 )
 
+// alertExpiry is how long we keep the state of an alert instance that is no longer evaluated, e.g., because the user
+// or fileset is gone
+var alertExpiry = 24 * time.Hour
+
 // gpfsbeat configuration.
 type gpfsbeat struct {
 	done   chan struct{}
 	config config.Config
 	client beat.Client
+
+	resolver          resolver.Resolver
+	quotaChangeFilter *quotaChangeFilter
+	alerts            *alerting.Engine
+	notifier          *notify.Notifier
+	previousFilesets  []parser.MmLsFilesetInfo
+	metadata          *metadata.Source
+	filesetPaths      map[[2]string]string
+	blockSizes        map[string]parser.MmLsFsAttributes
+
+	wg         sync.WaitGroup
+	storeMutex sync.Mutex
+	registry   *statestore.Registry
+	store      *statestore.Store
 }
 
 // New creates an instance of gpfsbeat.
@@ -44,6 +69,34 @@ func New(b *beat.Beat, cfg *common.Config) (beat.Beater, error) {
 		bt.config.Devices = devices
 		logp.Info("Renewed devices list: %s", bt.config.Devices)
 	}
+	if bt.config.Resolver.Enabled {
+		r, err := resolver.New(bt.config.Resolver)
+		if err != nil {
+			return nil, fmt.Errorf("Error setting up the resolver: %v", err)
+		}
+		bt.resolver = r
+		logp.Info("Resolving quota entities through %q", bt.config.Resolver.Sources)
+	}
+	if bt.config.Alerts.Enabled {
+		e, err := alerting.New(bt.config.Alerts.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("Error in the alert rules: %v", err)
+		}
+		bt.alerts = e
+		logp.Info("Evaluating %d alert rules", len(bt.config.Alerts.Rules))
+	}
+	if bt.config.Metadata.Enabled {
+		m, err := metadata.New(bt.config.Metadata.Path)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading the metadata mapping: %v", err)
+		}
+		bt.metadata = m
+		logp.Info("Enriching fileset and quota events with the metadata in %s", bt.config.Metadata.Path)
+	}
+	if bt.config.QuotaChangesOnly.Enabled {
+		bt.quotaChangeFilter = newQuotaChangeFilter(bt.config.QuotaChangesOnly)
+		logp.Info("Only publishing changed quota entries, with a full snapshot every %s", bt.config.QuotaChangesOnly.Heartbeat)
+	}
 	return bt, nil
 }
 
@@ -57,6 +110,36 @@ func (bt *gpfsbeat) Run(b *beat.Beat) error {
 		return err
 	}
 
+	if bt.config.MmFsLog {
+		logp.Info("Following %s", bt.config.MmFsLogPath)
+		bt.wg.Add(1)
+		go func() {
+			defer bt.wg.Done()
+			bt.tailMmFsLog(b)
+		}()
+	}
+
+	if bt.config.Audit {
+		logp.Info("Consuming file audit logs from %q", bt.config.AuditPaths)
+		bt.wg.Add(1)
+		go func() {
+			defer bt.wg.Done()
+			bt.consumeAuditLogs(b)
+		}()
+	}
+
+	if bt.config.Notify.Enabled {
+		store, err := bt.stateStore()
+		if err != nil {
+			return err
+		}
+		bt.notifier, err = notify.New(bt.config.Notify, store)
+		if err != nil {
+			return fmt.Errorf("Error setting up notifications: %v", err)
+		}
+		logp.Info("Notifying users about their %q quota", bt.config.Notify.Kinds)
+	}
+
 	ticker := time.NewTicker(bt.config.Period)
 	counter := 1
 	for {
@@ -66,20 +149,84 @@ func (bt *gpfsbeat) Run(b *beat.Beat) error {
 		case <-ticker.C:
 		}
 
+		if bt.metadata != nil {
+			bt.metadata.Reload()
+		}
+
+		// the filesets go first, so the quota events are matched on the junction paths of this cycle
+		mmlsfilesetinfos, filesetErr := bt.MmLsFileset()
+		logp.Info("Retrieved usage information from mmlsfileset")
+		if filesetErr == nil && bt.metadata != nil {
+			bt.rememberFilesetPaths(mmlsfilesetinfos)
+		}
+
 		gpfsQuota, err := bt.MmRepQuota()
 		logp.Info("retrieved quota information from mmrepquota")
+		if err == nil && bt.resolver != nil {
+			resolveQuotaEntities(bt.resolver, gpfsQuota)
+		}
+		if err == nil && bt.config.QuotaDefaults {
+			defaults, err := bt.MmRepQuotaDefaults()
+			if err == nil {
+				parser.FlagQuotaDefaults(gpfsQuota, defaults)
+				for i := range defaults {
+					bt.publishMapStr(b, counter, "quota_defaults", defaults[i].ToMapStr())
+				}
+				logp.Info("quota defaults events sent")
+			} else {
+				logp.Err("Could not retrieve quota defaults information")
+			}
+		}
+		if err == nil && bt.config.QuotaLimitChanges {
+			store, err := bt.stateStore()
+			if err == nil {
+				changed, old := quotaLimitChanges(store, gpfsQuota)
+				for i := range changed {
+					bt.publishMapStr(b, counter, "quota_limit_change", changed[i].LimitChangeMapStr(old[i]))
+				}
+				logp.Info("%d quota_limit_change events sent", len(changed))
+			} else {
+				logp.Err("Cannot detect quota limit changes without a registry")
+			}
+		}
+		if err == nil && bt.config.QuotaSummary {
+			summaries := parser.AggregateQuotaPerEntity(gpfsQuota)
+			for i := range summaries {
+				bt.publishMapStr(b, counter, "quota_summary", summaries[i].ToMapStr())
+			}
+			logp.Info("quota_summary events sent")
+		}
+		if err == nil && bt.config.QuotaTopN > 0 {
+			tops := parser.TopQuotaConsumersPerFileset(gpfsQuota, bt.config.QuotaTopN)
+			for i := range tops {
+				bt.publishMapStr(b, counter, "quota_top", tops[i].ToMapStr())
+			}
+			logp.Info("quota_top events sent")
+		}
+		if err == nil && bt.notifier != nil {
+			sent := bt.notifier.Process(gpfsQuota, time.Now())
+			logp.Info("%d quota notifications sent", sent)
+		}
+		// the other consumers of the quota information still need all entries
+		publishedQuota := gpfsQuota
+		if err == nil && bt.quotaChangeFilter != nil {
+			var snapshot bool
+			publishedQuota, snapshot = bt.quotaChangeFilter.filter(gpfsQuota, time.Now())
+			logp.Info("%d quota entries to publish (full snapshot: %t)", len(publishedQuota), snapshot)
+		}
 		if err == nil {
-			for _, q := range gpfsQuota {
-				quota := q.ToMapStr()
-				event := beat.Event{
-					Timestamp: time.Now(),
-					Fields: common.MapStr{
-						"type":    b.Info.Name,
-						"counter": counter,
-						"quota":   quota,
-					},
+			published := make(map[parser.QuotaKey]bool, len(publishedQuota))
+			for i := range publishedQuota {
+				published[publishedQuota[i].Key()] = true
+			}
+			// the alert rules need to see all entries, also those the change filter holds back
+			for i := range gpfsQuota {
+				info := gpfsQuota[i].ToMapStr()
+				bt.enrichMetadata("quota", info)
+				if published[gpfsQuota[i].Key()] {
+					bt.publishEvent(b, counter, "quota", info)
 				}
-				bt.client.Publish(event)
+				bt.evaluateAlerts(b, "quota", info)
 			}
 			logp.Info("mmrepquota events sent")
 		} else {
@@ -89,49 +236,162 @@ func (bt *gpfsbeat) Run(b *beat.Beat) error {
 		mmdfinfos, err := bt.MmDf()
 		logp.Info("Retrieved usage information from mmdf")
 		if err == nil {
-			for _, i := range mmdfinfos {
-				info := i.ToMapStr()
-				event := beat.Event{
-					Timestamp: time.Now(),
-					Fields: common.MapStr{
-						"type":    b.Info.Name,
-						"counter": counter,
-						"mmdf":    info,
-					},
-				}
-				bt.client.Publish(event)
+			// the block sizes do not change, so we only ask for them until we have them
+			if bt.blockSizes == nil {
+				bt.blockSizes, _ = bt.MmLsFsAttributes()
 			}
+			parser.ApplyMmLsFsAttributes(mmdfinfos, bt.blockSizes)
+			bt.publishResults(b, counter, "mmdf", mmdfinfos)
 			logp.Info("mmdf events sent")
+
+			if bt.config.NSDBalance.Enabled {
+				for _, nb := range parser.AnalyzeNSDBalance(mmdfinfos, bt.config.NSDBalance.Threshold) {
+					bt.publishMapStr(b, counter, "nsd_balance", nb.ToMapStr())
+				}
+				logp.Info("nsd_balance events sent")
+			}
+
+			if bt.config.CapacitySplit {
+				for _, cs := range parser.SplitCapacity(mmdfinfos) {
+					bt.publishMapStr(b, counter, "capacity_split", cs.ToMapStr())
+				}
+				logp.Info("capacity_split events sent")
+			}
 		} else {
 			logp.Err("Could not retrieve mmdf information")
 		}
 
-		mmlsfilesetinfos, err := bt.MmLsFileset()
-		logp.Info("Retrieved usage information from mmlsfileset")
-		if err == nil {
-			for _, i := range mmlsfilesetinfos {
-				info := i.ToMapStr()
-				event := beat.Event{
-					Timestamp: time.Now(),
-					Fields: common.MapStr{
-						"type":        b.Info.Name,
-						"counter":     counter,
-						"mmlsfileset": info,
-					},
+		if bt.config.Forecast.Enabled {
+			store, err := bt.stateStore()
+			if err == nil {
+				forecasts := forecastCapacity(store, bt.config.Forecast, capacityUsages(gpfsQuota, mmdfinfos), time.Now())
+				for _, f := range forecasts {
+					bt.publishMapStr(b, counter, "forecast", f)
 				}
-				bt.client.Publish(event)
+				logp.Info("forecast events sent")
+			} else {
+				logp.Err("Cannot forecast capacity without a registry")
+			}
+		}
+
+		if filesetErr == nil {
+			for _, i := range mmlsfilesetinfos {
+				bt.publishMapStr(b, counter, "mmlsfileset", i.ToMapStr())
 			}
 			logp.Info("mmlsfileset events sent")
+
+			// gpfsQuota is nil when mmrepquota failed, we do not want to publish filesets without usage then
+			if bt.config.FilesetUsage && gpfsQuota != nil {
+				for _, u := range parser.JoinFilesetQuota(mmlsfilesetinfos, gpfsQuota) {
+					bt.publishMapStr(b, counter, "fileset_usage", u.ToMapStr())
+				}
+				logp.Info("fileset_usage events sent")
+			}
+
+			if bt.config.InodeSpaces.Enabled {
+				for _, s := range parser.InodeSpaces(mmlsfilesetinfos, bt.config.InodeSpaces.NearMaxPercentage) {
+					bt.publishMapStr(b, counter, "inode_space", s.ToMapStr())
+				}
+				logp.Info("inode_space events sent")
+			}
+
+			// the first run only serves as the reference for the next one
+			if bt.config.FilesetLifecycle {
+				if bt.previousFilesets != nil {
+					for _, e := range parser.DiffMmLsFileset(bt.previousFilesets, mmlsfilesetinfos) {
+						bt.publishMapStr(b, counter, "fileset_lifecycle", e.ToMapStr())
+					}
+					logp.Info("fileset_lifecycle events sent")
+				}
+				bt.previousFilesets = mmlsfilesetinfos
+			}
 		} else {
 			logp.Err("Could not retrieve mmlsfileset information")
 		}
 
+		if bt.config.GNR {
+			gnrinfos, err := bt.GNR()
+			logp.Info("Retrieved recovery group information from mmvdisk and mmlsrecoverygroup")
+			if err == nil {
+				bt.publishResults(b, counter, "gnr", gnrinfos)
+				logp.Info("gnr events sent")
+			} else {
+				logp.Err("Could not retrieve gnr information")
+			}
+		}
+
+		if bt.config.QoS {
+			qosinfos, err := bt.MmLsQos()
+			logp.Info("Retrieved QoS information from mmlsqos")
+			if err == nil {
+				bt.publishResults(b, counter, "mmlsqos", qosinfos)
+				logp.Info("mmlsqos events sent")
+			} else {
+				logp.Err("Could not retrieve mmlsqos information")
+			}
+		}
+
+		if bt.alerts != nil {
+			bt.publishAlerts(b, bt.alerts.Expire(time.Now().Add(-alertExpiry)))
+		}
+
 		counter++
 	}
 }
 
+// publishResults sends one event per parse result, with the information under the given key
+func (bt *gpfsbeat) publishResults(b *beat.Beat, counter int, key string, results []parser.ParseResult) {
+	for _, r := range results {
+		bt.publishMapStr(b, counter, key, r.ToMapStr())
+	}
+}
+
+// publishMapStr enriches the information, sends it in a single event under the given key and runs the alert rules
+func (bt *gpfsbeat) publishMapStr(b *beat.Beat, counter int, key string, info common.MapStr) {
+	bt.enrichMetadata(key, info)
+	bt.publishEvent(b, counter, key, info)
+	bt.evaluateAlerts(b, key, info)
+}
+
+// publishEvent sends a single event with the information under the given key
+func (bt *gpfsbeat) publishEvent(b *beat.Beat, counter int, key string, info common.MapStr) {
+	event := beat.Event{
+		Timestamp: time.Now(),
+		Fields: common.MapStr{
+			"type":    b.Info.Name,
+			"counter": counter,
+			key:       info,
+		},
+	}
+	bt.client.Publish(event)
+}
+
+// evaluateAlerts runs the alert rules on the information published under the given key
+func (bt *gpfsbeat) evaluateAlerts(b *beat.Beat, key string, info common.MapStr) {
+	if bt.alerts == nil {
+		return
+	}
+	bt.publishAlerts(b, bt.alerts.Evaluate(key, info, time.Now()))
+}
+
+// publishAlerts sends an event for each alert state change
+func (bt *gpfsbeat) publishAlerts(b *beat.Beat, alerts []common.MapStr) {
+	for _, alert := range alerts {
+		event := beat.Event{
+			Timestamp: time.Now(),
+			Fields: common.MapStr{
+				"type":  b.Info.Name,
+				"alert": alert,
+			},
+		}
+		bt.client.Publish(event)
+	}
+}
+
 // Stop stops gpfsbeat.
 func (bt *gpfsbeat) Stop() {
-	bt.client.Close()
 	close(bt.done)
+	bt.wg.Wait()
+	bt.client.Close()
+	bt.closeStateStore()
 }
@@ -0,0 +1,48 @@
+package beater
+
+import (
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+// metadataFields lists, for the events we enrich, the fields holding the filesystem, fileset and junction path.
+// Events without a path field are matched on the junction paths from the mmlsfileset run at the start of the cycle.
+var metadataFields = map[string][3]string{
+	"quota":             {"filesystem", "fileset", ""},
+	"quota_top":         {"filesystem", "fileset", ""},
+	"mmlsfileset":       {"filesystem_name", "fileset_name", "path"},
+	"fileset_usage":     {"filesystem", "fileset", "path"},
+	"fileset_lifecycle": {"filesystem", "fileset", ""},
+}
+
+// rememberFilesetPaths keeps the junction paths, so events lacking one can still be matched on a path prefix
+func (bt *gpfsbeat) rememberFilesetPaths(filesets []parser.MmLsFilesetInfo) {
+	bt.filesetPaths = make(map[[2]string]string, len(filesets))
+	for i := range filesets {
+		f := &filesets[i]
+		bt.filesetPaths[[2]string{f.Filesystem(), f.Name()}] = f.Path()
+	}
+}
+
+// enrichMetadata adds the site metadata of the fileset to the information published under the given key
+func (bt *gpfsbeat) enrichMetadata(key string, info common.MapStr) {
+	fields, ok := metadataFields[key]
+	if bt.metadata == nil || !ok {
+		return
+	}
+
+	filesystem, _ := info[fields[0]].(string)
+	fileset, _ := info[fields[1]].(string)
+	var path string
+	if fields[2] != "" {
+		path, _ = info[fields[2]].(string)
+	} else {
+		path = bt.filesetPaths[[2]string{filesystem, fileset}]
+	}
+
+	if e, ok := bt.metadata.Lookup(filesystem, fileset, path); ok {
+		info["metadata"] = e.ToMapStr(time.Now())
+	}
+}
@@ -0,0 +1,142 @@
+package beater
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+var mmfslogPollInterval = 1 * time.Second
+
+// mmfsLogTailer follows mmfs.log.latest, which GPFS replaces by a new file (and symlink) when rotating
+type mmfsLogTailer struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	reader  *bufio.Reader
+	offset  int64
+	partial string
+}
+
+// open (re)opens the log file. When fromEnd is set, we skip the existing content.
+func (t *mmfsLogTailer) open(fromEnd bool) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t.offset = 0
+	if fromEnd {
+		t.offset, err = f.Seek(0, io.SeekEnd)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	t.file = f
+	t.info = info
+	t.reader = bufio.NewReader(f)
+	t.partial = ""
+	return nil
+}
+
+// close closes the currently followed file, if any
+func (t *mmfsLogTailer) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+// readLines returns all complete lines that were appended since the last call
+func (t *mmfsLogTailer) readLines() []string {
+	var lines []string
+	for {
+		s, err := t.reader.ReadString('\n')
+		t.offset += int64(len(s))
+		if err != nil {
+			t.partial += s // wait for the rest of the line
+			return lines
+		}
+		lines = append(lines, strings.TrimRight(t.partial+s, "\n"))
+		t.partial = ""
+	}
+}
+
+// rotated checks if the path now points to a different file or if the file was truncated
+func (t *mmfsLogTailer) rotated() bool {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return false // the new file may not be there yet, keep reading the old one
+	}
+	if !os.SameFile(info, t.info) {
+		return true
+	}
+	return info.Size() < t.offset
+}
+
+// poll reads the new lines, and switches to the new file after a rotation
+func (t *mmfsLogTailer) poll() []string {
+	if t.file == nil {
+		if err := t.open(false); err != nil {
+			return nil
+		}
+	}
+	lines := t.readLines()
+	if t.rotated() {
+		logp.Info("%s was rotated, reopening", t.path)
+		lines = append(lines, t.readLines()...) // drain whatever was written before the rotation
+		t.close()
+		if err := t.open(false); err == nil {
+			lines = append(lines, t.readLines()...)
+		}
+	}
+	return lines
+}
+
+// tailMmFsLog follows the mmfs log and publishes every line until the beat is stopped
+func (bt *gpfsbeat) tailMmFsLog(b *beat.Beat) {
+	t := &mmfsLogTailer{path: bt.config.MmFsLogPath}
+	if err := t.open(true); err != nil {
+		logp.Err("Cannot open %s, will keep trying. Error: %s", t.path, err)
+	}
+	defer t.close()
+
+	ticker := time.NewTicker(mmfslogPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-bt.done:
+			return
+		case <-ticker.C:
+		}
+
+		for _, line := range t.poll() {
+			entry, ok := parser.ParseMmFsLogLine(line)
+			if !ok {
+				continue // e.g., the continuation lines of a waiter dump
+			}
+			info := entry.ToMapStr()
+			event := beat.Event{
+				Timestamp: entry.Timestamp(),
+				Fields: common.MapStr{
+					"type":    b.Info.Name,
+					"mmfslog": info,
+				},
+			}
+			bt.client.Publish(event)
+			bt.evaluateAlerts(b, "mmfslog", info)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+//go:build !integration
+
+package beater
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func appendLog(t *testing.T, path string, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMmFsLogTailer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mmfs.log.latest")
+	appendLog(t, path, "old line, skipped on start\n")
+
+	tailer := &mmfsLogTailer{path: path}
+	if err := tailer.open(true); err != nil {
+		t.Fatal(err)
+	}
+	defer tailer.close()
+
+	if lines := tailer.poll(); len(lines) != 0 {
+		t.Errorf("expected no lines, got %q", lines)
+	}
+
+	// partial lines are only returned once complete
+	appendLog(t, path, "line 1\nline ")
+	if lines := tailer.poll(); !reflect.DeepEqual(lines, []string{"line 1"}) {
+		t.Errorf("expected line 1, got %q", lines)
+	}
+	appendLog(t, path, "2\n")
+	if lines := tailer.poll(); !reflect.DeepEqual(lines, []string{"line 2"}) {
+		t.Errorf("expected line 2, got %q", lines)
+	}
+
+	// GPFS rotates by moving the log away and starting a new file; the lines written to the old file before the
+	// rotation must not be lost
+	appendLog(t, path, "line 3\n")
+	if err := os.Rename(path, filepath.Join(dir, "mmfs.log.2023.03.09")); err != nil {
+		t.Fatal(err)
+	}
+	appendLog(t, path, "line 4\n")
+	if lines := tailer.poll(); !reflect.DeepEqual(lines, []string{"line 3", "line 4"}) {
+		t.Errorf("expected lines 3 and 4 across the rotation, got %q", lines)
+	}
+
+	// truncation starts from the beginning of the file
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendLog(t, path, "5\n")
+	if lines := tailer.poll(); !reflect.DeepEqual(lines, []string{"5"}) {
+		t.Errorf("expected line 5 after truncation, got %q", lines)
+	}
+}
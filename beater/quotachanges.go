@@ -0,0 +1,54 @@
+package beater
+
+import (
+	"time"
+
+	"github.com/hpcugent/gpfsbeat/config"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+// quotaChangeFilter remembers the last published state of each quota entry, so we only publish the entries that changed
+type quotaChangeFilter struct {
+	config        config.QuotaChangesConfig
+	published     map[parser.QuotaKey]parser.QuotaInfo
+	lastHeartbeat time.Time
+}
+
+func newQuotaChangeFilter(c config.QuotaChangesConfig) *quotaChangeFilter {
+	return &quotaChangeFilter{
+		config:    c,
+		published: make(map[parser.QuotaKey]parser.QuotaInfo),
+	}
+}
+
+// filter returns the quota entries that should be published now, and whether this is a full snapshot
+func (f *quotaChangeFilter) filter(quotas []parser.QuotaInfo, now time.Time) ([]parser.QuotaInfo, bool) {
+	heartbeat := now.Sub(f.lastHeartbeat) >= f.config.Heartbeat
+	if heartbeat {
+		f.lastHeartbeat = now
+	}
+
+	var changed []parser.QuotaInfo
+	seen := make(map[parser.QuotaKey]bool, len(quotas))
+	for i := range quotas {
+		q := &quotas[i]
+		key := q.Key()
+		seen[key] = true
+
+		previous, ok := f.published[key]
+		if heartbeat || !ok || q.LimitsDiffer(&previous) ||
+			q.UsageDiffers(&previous, f.config.BlockDelta, f.config.FilesDelta) {
+			changed = append(changed, *q)
+			f.published[key] = *q
+		}
+	}
+
+	// entries that disappeared should be published again if they ever return
+	for key := range f.published {
+		if !seen[key] {
+			delete(f.published, key)
+		}
+	}
+
+	return changed, heartbeat
+}
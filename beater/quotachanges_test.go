@@ -0,0 +1,86 @@
+//go:build !integration
+
+package beater
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hpcugent/gpfsbeat/config"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+const testQuotaHeader = "mmrepquota::HEADER:version:reserved:reserved:filesystemName:quotaType:id:name:blockUsage:blockQuota:blockLimit:blockInDoubt:blockGrace:filesUsage:filesQuota:filesLimit:filesInDoubt:filesGrace:remarks:quota:defQuota:fid:filesetname:"
+
+// parseTestQuota parses mmrepquota -Y lines for fs1, each line given as the fields from quotaType up to filesGrace
+func parseTestQuota(t *testing.T, lines ...string) []parser.QuotaInfo {
+	t.Helper()
+	output := []string{testQuotaHeader}
+	for _, l := range lines {
+		output = append(output, "mmrepquota::0:1:::fs1:"+l+":e:on:on:1:data:")
+	}
+	quotas, err := parser.ParseMmRepQuota(strings.Join(output, "\n") + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return quotas
+}
+
+// entities returns the entities of the quota entries, in order
+func entities(quotas []parser.QuotaInfo) string {
+	var names []string
+	for i := range quotas {
+		names = append(names, quotas[i].Key().Entity)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestQuotaChangeFilter(t *testing.T) {
+	f := newQuotaChangeFilter(config.QuotaChangesConfig{BlockDelta: 100, FilesDelta: 10, Heartbeat: time.Hour})
+	start := time.Now()
+
+	quotas := parseTestQuota(t,
+		"USR:1000:alice:1000:5000:6000:0:none:10:0:0:0:none",
+		"USR:1001:bob:1000:5000:6000:0:none:10:0:0:0:none",
+		"USR:1002:carol:1000:5000:6000:0:none:10:0:0:0:none",
+	)
+	published, snapshot := f.filter(quotas, start)
+	if !snapshot || entities(published) != "alice,bob,carol" {
+		t.Fatalf("expected a full snapshot first, got %s (%t)", entities(published), snapshot)
+	}
+
+	// small moves stay below the deltas, but they add up against the last published usage
+	quotas = parseTestQuota(t,
+		"USR:1000:alice:1050:5000:6000:0:none:15:0:0:0:none",
+		"USR:1001:bob:1000:5000:7000:0:none:10:0:0:0:none",
+		"USR:1002:carol:1000:5000:6000:0:none:21:0:0:0:none",
+	)
+	published, snapshot = f.filter(quotas, start.Add(time.Minute))
+	if snapshot || entities(published) != "bob,carol" {
+		t.Errorf("expected the limit and files changes, got %s (%t)", entities(published), snapshot)
+	}
+	quotas = parseTestQuota(t,
+		"USR:1000:alice:1101:5000:6000:0:none:15:0:0:0:none",
+		"USR:1001:bob:1000:5000:7000:0:none:10:0:0:0:none",
+	)
+	published, _ = f.filter(quotas, start.Add(2*time.Minute))
+	if entities(published) != "alice" {
+		t.Errorf("expected the accumulated block change, got %s", entities(published))
+	}
+
+	// carol disappeared, so she is published as soon as she is back
+	quotas = parseTestQuota(t,
+		"USR:1000:alice:1101:5000:6000:0:none:15:0:0:0:none",
+		"USR:1002:carol:1000:5000:6000:0:none:21:0:0:0:none",
+	)
+	published, _ = f.filter(quotas, start.Add(3*time.Minute))
+	if entities(published) != "carol" {
+		t.Errorf("expected the returning entry, got %s", entities(published))
+	}
+
+	published, snapshot = f.filter(quotas, start.Add(time.Hour))
+	if !snapshot || entities(published) != "alice,carol" {
+		t.Errorf("expected a full snapshot after the heartbeat, got %s (%t)", entities(published), snapshot)
+	}
+}
@@ -0,0 +1,71 @@
+package beater
+
+import (
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+// quotaLimitsRegistryPrefix prefixes the keys of the last seen quota limits in the registry
+const quotaLimitsRegistryPrefix = "quota_limits::"
+
+// quotaLimitsKey returns the registry key for the quota entry
+func quotaLimitsKey(k parser.QuotaKey) string {
+	return quotaLimitsRegistryPrefix + strings.Join([]string{k.Filesystem, k.Fileset, k.Kind, k.Entity}, "/")
+}
+
+// quotaLimitChanges compares the limits with those of the previous mmrepquota run, which are kept in the registry so
+// the comparison survives restarts. It returns the quota entries whose limits changed, together with the old limits.
+func quotaLimitChanges(store *statestore.Store, quotas []parser.QuotaInfo) ([]parser.QuotaInfo, []parser.QuotaLimits) {
+	var changed []parser.QuotaInfo
+	var old []parser.QuotaLimits
+
+	seen := make(map[string]bool, len(quotas))
+	for i := range quotas {
+		q := &quotas[i]
+		key := quotaLimitsKey(q.Key())
+		seen[key] = true
+		current := q.Limits()
+
+		var previous parser.QuotaLimits
+		known, err := store.Has(key)
+		if err == nil && known {
+			err = store.Get(key, &previous)
+		}
+		if err != nil {
+			logp.Err("Cannot read the previous quota limits for %s. Error: %s", key, err)
+			continue
+		}
+		if known && previous == current {
+			continue
+		}
+		if known {
+			changed = append(changed, *q)
+			old = append(old, previous)
+		}
+		if err := store.Set(key, current); err != nil {
+			logp.Err("Cannot store the quota limits for %s. Error: %s", key, err)
+		}
+	}
+
+	// forget about the entries that are gone
+	var gone []string
+	err := store.Each(func(key string, _ statestore.ValueDecoder) (bool, error) {
+		if strings.HasPrefix(key, quotaLimitsRegistryPrefix) && !seen[key] {
+			gone = append(gone, key)
+		}
+		return true, nil
+	})
+	if err != nil {
+		logp.Err("Cannot list the quota limits in the registry. Error: %s", err)
+	}
+	for _, key := range gone {
+		if err := store.Remove(key); err != nil {
+			logp.Err("Cannot remove the registry entry %s. Error: %s", key, err)
+		}
+	}
+
+	return changed, old
+}
@@ -0,0 +1,54 @@
+//go:build !integration
+
+package beater
+
+import (
+	"testing"
+
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+func TestQuotaLimitChanges(t *testing.T) {
+	store := newTestStore(t)
+
+	quotas := parseTestQuota(t,
+		"USR:1000:alice:1000:5000:6000:0:none:10:100:200:0:none",
+		"USR:1001:bob:1000:5000:6000:0:none:10:100:200:0:none",
+	)
+	if changed, _ := quotaLimitChanges(store, quotas); len(changed) != 0 {
+		t.Fatalf("the first run should only record the limits, got %s", entities(changed))
+	}
+
+	// usage changes are not limit changes
+	quotas = parseTestQuota(t,
+		"USR:1000:alice:3000:5000:7000:0:none:10:100:200:0:none",
+		"USR:1001:bob:2000:5000:6000:0:none:50:100:200:0:none",
+	)
+	changed, old := quotaLimitChanges(store, quotas)
+	if entities(changed) != "alice" {
+		t.Fatalf("expected the limit change of alice, got %s", entities(changed))
+	}
+	expected := parser.QuotaLimits{BlockSoft: 5000, BlockHard: 6000, FilesSoft: 100, FilesHard: 200}
+	if old[0] != expected {
+		t.Errorf("expected the old limits %+v, got %+v", expected, old[0])
+	}
+	if c := changed[0].LimitChangeMapStr(old[0])["changed"].([]string); len(c) != 1 || c[0] != "block_hard" {
+		t.Errorf("expected only block_hard to change, got %q", c)
+	}
+
+	// bob is forgotten when he disappears, so his return is not a change
+	quotas = parseTestQuota(t, "USR:1000:alice:3000:5000:7000:0:none:10:100:200:0:none")
+	if changed, _ := quotaLimitChanges(store, quotas); len(changed) != 0 {
+		t.Errorf("expected no changes, got %s", entities(changed))
+	}
+	if ok, _ := store.Has(quotaLimitsKey(parser.QuotaKey{Filesystem: "fs1", Fileset: "data", Kind: "USR", Entity: "bob"})); ok {
+		t.Error("expected the limits of bob to be removed from the registry")
+	}
+	quotas = parseTestQuota(t,
+		"USR:1000:alice:3000:5000:7000:0:none:10:100:200:0:none",
+		"USR:1001:bob:2000:1:2:0:none:50:100:200:0:none",
+	)
+	if changed, _ := quotaLimitChanges(store, quotas); len(changed) != 0 {
+		t.Errorf("expected no changes for a returning entry, got %s", entities(changed))
+	}
+}
@@ -0,0 +1,30 @@
+package beater
+
+import (
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/hpcugent/gpfsbeat/config"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+// CollectQuotaAndFilesets runs mmrepquota and mmlsfileset once, for the reports that run outside of the beat
+func CollectQuotaAndFilesets(c config.Config) ([]parser.QuotaInfo, []parser.MmLsFilesetInfo, error) {
+	bt := &gpfsbeat{config: c}
+	if len(bt.config.Devices) == 1 && bt.config.Devices[0] == "all" {
+		devices, err := bt.MmLsFs()
+		if err != nil {
+			return nil, nil, err
+		}
+		bt.config.Devices = devices
+	}
+	logp.Info("Collecting quota and fileset information from devices %q", bt.config.Devices)
+
+	quotas, err := bt.MmRepQuota()
+	if err != nil {
+		return nil, nil, err
+	}
+	filesets, err := bt.MmLsFileset()
+	if err != nil {
+		return nil, nil, err
+	}
+	return quotas, filesets, nil
+}
@@ -0,0 +1,24 @@
+package beater
+
+import (
+	"github.com/hpcugent/gpfsbeat/parser"
+	"github.com/hpcugent/gpfsbeat/resolver"
+)
+
+// resolveQuotaEntities fills in the missing ID or name of the users and groups in the quota entries
+func resolveQuotaEntities(r resolver.Resolver, quotas []parser.QuotaInfo) {
+	for i := range quotas {
+		q := &quotas[i]
+		if q.Kind() != resolver.User && q.Kind() != resolver.Group {
+			continue
+		}
+		id, name := q.EntityID(), q.EntityName()
+		if name == "" && id != "" {
+			name, _ = r.Name(q.Kind(), id)
+		}
+		if id == "" && name != "" {
+			id, _ = r.ID(q.Kind(), name)
+		}
+		q.SetEntity(id, name)
+	}
+}
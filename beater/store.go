@@ -0,0 +1,53 @@
+package beater
+
+import (
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend/memlog"
+)
+
+// stateStore returns the store in which gpfsbeat keeps the state that needs to survive restarts, it is
+// opened on first use so that setups not needing any state do not create a registry
+func (bt *gpfsbeat) stateStore() (*statestore.Store, error) {
+	bt.storeMutex.Lock()
+	defer bt.storeMutex.Unlock()
+
+	if bt.store != nil {
+		return bt.store, nil
+	}
+
+	backend, err := memlog.New(logp.NewLogger("gpfsbeat-registry"), memlog.Settings{
+		Root:     paths.Resolve(paths.Data, "registry"),
+		FileMode: 0600,
+	})
+	if err != nil {
+		logp.Err("Cannot open the gpfsbeat registry. Error: %s", err)
+		return nil, err
+	}
+
+	bt.registry = statestore.NewRegistry(backend)
+	bt.store, err = bt.registry.Get("gpfsbeat")
+	if err != nil {
+		logp.Err("Cannot open the gpfsbeat store in the registry. Error: %s", err)
+		bt.registry.Close()
+		bt.registry = nil
+		return nil, err
+	}
+	return bt.store, nil
+}
+
+// closeStateStore closes the store and registry if they were opened
+func (bt *gpfsbeat) closeStateStore() {
+	bt.storeMutex.Lock()
+	defer bt.storeMutex.Unlock()
+
+	if bt.store != nil {
+		bt.store.Close()
+		bt.store = nil
+	}
+	if bt.registry != nil {
+		bt.registry.Close()
+		bt.registry = nil
+	}
+}
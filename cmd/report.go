@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/cfgfile"
+	"github.com/spf13/cobra"
+
+	"github.com/hpcugent/gpfsbeat/beater"
+	"github.com/hpcugent/gpfsbeat/config"
+	"github.com/hpcugent/gpfsbeat/metadata"
+	"github.com/hpcugent/gpfsbeat/parser"
+	"github.com/hpcugent/gpfsbeat/report"
+)
+
+// genReportCmd returns the `report` command, which groups the report subcommands
+func genReportCmd() *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Generate reports from the GPFS information",
+	}
+	reportCmd.AddCommand(genChargebackCmd())
+	return reportCmd
+}
+
+// loadConfig reads the gpfsbeat section of the configuration file given with -c, as the beat does when it starts
+func loadConfig() (config.Config, error) {
+	c := config.DefaultConfig
+	cfg, err := cfgfile.Load("", nil)
+	if err != nil {
+		return c, fmt.Errorf("cannot load the configuration: %v", err)
+	}
+	if !cfg.HasField(Name) {
+		return c, nil
+	}
+	beatCfg, err := cfg.Child(Name, -1)
+	if err != nil {
+		return c, fmt.Errorf("cannot read the %s configuration: %v", Name, err)
+	}
+	if err := beatCfg.Unpack(&c); err != nil {
+		return c, fmt.Errorf("cannot read the %s configuration: %v", Name, err)
+	}
+	return c, nil
+}
+
+// readCaptured reads a file with captured command output and hands it to parse. The parsers panic on fields
+// they cannot convert, so such a panic is reported as a parse error as well.
+func readCaptured(command string, path string, parse func(string) error) (err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read captured %s output: %v", command, err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot parse captured %s output %s: %v", command, path, r)
+		}
+	}()
+	if err := parse(string(data)); err != nil {
+		return fmt.Errorf("cannot parse captured %s output %s: %v", command, path, err)
+	}
+	return nil
+}
+
+// genChargebackCmd returns the `report chargeback` command
+func genChargebackCmd() *cobra.Command {
+	var (
+		devices        []string
+		quotaOutputs   []string
+		filesetOutputs []string
+		metadataPath   string
+		period         time.Duration
+		usage          string
+		format         string
+		outputPath     string
+	)
+
+	chargebackCmd := &cobra.Command{
+		Use:   "chargeback",
+		Short: "Report the fileset usage per project",
+		Long: "Report the FILESET quota usage grouped by the project in the metadata mapping file. The usage is\n" +
+			"collected by running mmrepquota and mmlsfileset once, or read from captured `mmrepquota -Y` and\n" +
+			"`mmlsfileset -L -Y` output. Every captured mmrepquota file counts as a sample, and the average over\n" +
+			"the samples is charged for the whole period. The devices and the metadata mapping file default to those\n" +
+			"in the beat configuration.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if usage != report.UsageAverage && usage != report.UsageByteHours {
+				return fmt.Errorf("unknown usage %s, expected %s or %s", usage, report.UsageAverage, report.UsageByteHours)
+			}
+			if format != "csv" && format != "json" {
+				return fmt.Errorf("unknown format %s, expected csv or json", format)
+			}
+			if len(filesetOutputs) > 0 && len(quotaOutputs) == 0 {
+				return fmt.Errorf("--fileset-output can only be used together with --quota-output")
+			}
+			c, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if len(devices) > 0 {
+				c.Devices = devices
+			}
+			if metadataPath == "" && c.Metadata.Enabled {
+				metadataPath = c.Metadata.Path
+			}
+
+			var samples [][]parser.QuotaInfo
+			var filesets []parser.MmLsFilesetInfo
+			if len(quotaOutputs) == 0 {
+				quotas, fs, err := beater.CollectQuotaAndFilesets(c)
+				if err != nil {
+					return fmt.Errorf("cannot collect the quota information: %v", err)
+				}
+				samples = append(samples, quotas)
+				filesets = fs
+			} else {
+				for _, path := range quotaOutputs {
+					err := readCaptured("mmrepquota", path, func(output string) error {
+						quotas, err := parser.ParseMmRepQuota(output)
+						samples = append(samples, quotas)
+						return err
+					})
+					if err != nil {
+						return err
+					}
+				}
+				for _, path := range filesetOutputs {
+					err := readCaptured("mmlsfileset", path, func(output string) error {
+						fs, err := parser.ParseMmLsFileset("", output)
+						filesets = append(filesets, fs...)
+						return err
+					})
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			var m *metadata.Source
+			if metadataPath != "" {
+				m, err = metadata.New(metadataPath)
+				if err != nil {
+					return err
+				}
+			}
+			rows := report.Chargeback(samples, filesets, m, period)
+
+			var w io.Writer = os.Stdout
+			if outputPath != "" {
+				f, err := os.Create(outputPath)
+				if err != nil {
+					return fmt.Errorf("cannot create the report: %v", err)
+				}
+				defer f.Close()
+				w = f
+			}
+			if format == "json" {
+				return report.WriteJSON(w, rows, usage)
+			}
+			return report.WriteCSV(w, rows, usage)
+		},
+	}
+
+	flags := chargebackCmd.Flags()
+	flags.StringSliceVar(&devices, "devices", nil, "Devices to collect the usage from, instead of those in the configuration")
+	flags.StringSliceVar(&quotaOutputs, "quota-output", nil, "Captured mmrepquota -Y output to read instead of running mmrepquota, one file per sample")
+	flags.StringSliceVar(&filesetOutputs, "fileset-output", nil, "Captured mmlsfileset -L -Y output providing the junction paths, requires --quota-output")
+	flags.StringVar(&metadataPath, "metadata", "", "Metadata mapping file (.yml, .yaml, .json or .csv) assigning filesets to projects, instead of the one in the configuration")
+	flags.DurationVar(&period, "period", 30*24*time.Hour, "Length of the reporting period")
+	flags.StringVar(&usage, "usage", report.UsageAverage, "Usage measure: average or byte-hours")
+	flags.StringVar(&format, "format", "csv", "Output format: csv or json")
+	flags.StringVarP(&outputPath, "output", "o", "", "Write the report to this file instead of stdout")
+
+	return chargebackCmd
+}
@@ -13,3 +13,6 @@ var Name = "gpfsbeat"
 // RootCmd to handle beats cli
 var RootCmd = cmd.GenRootCmdWithSettings(beater.New, instance.Settings{Name: Name})
 
+func init() {
+	RootCmd.AddCommand(genReportCmd())
+}
@@ -9,20 +9,239 @@ import (
 
 // Config items
 type Config struct {
-	Period             time.Duration `config:"period"`
-	Devices            []string      `config:"devices"`
-	MMRepQuotaCommand  string        `config:"mmrepquota"`
-	MMLsFsCommand      string        `config:"mmlsfs"`
-	MMDfCommand        string        `config:"mmdf"`
-	MMLsFilesetCommand string        `config:"mmlsfileset"`
+	Period                   time.Duration `config:"period"`
+	Devices                  []string      `config:"devices"`
+	MMRepQuotaCommand        string        `config:"mmrepquota"`
+	MMLsFsCommand            string        `config:"mmlsfs"`
+	MMDfCommand              string        `config:"mmdf"`
+	MMLsFilesetCommand       string        `config:"mmlsfileset"`
+	MMLsFilesetAFM           bool          `config:"mmlsfileset_afm"`
+	GNR                      bool          `config:"gnr"`
+	MMVdiskCommand           string        `config:"mmvdisk"`
+	MMLsRecoveryGroupCommand string        `config:"mmlsrecoverygroup"`
+	QoS                      bool          `config:"qos"`
+	QoSSeconds               int           `config:"qos_seconds"`
+	MMLsQosCommand           string        `config:"mmlsqos"`
+	MmFsLog                  bool          `config:"mmfslog"`
+	MmFsLogPath              string        `config:"mmfslog_path"`
+	Audit                    bool          `config:"audit"`
+	AuditPaths               []string      `config:"audit_paths"`
+	QuotaDefaults            bool          `config:"quota_defaults"`
+	QuotaLimitChanges        bool          `config:"quota_limit_changes"`
+	QuotaSummary             bool          `config:"quota_summary"`
+	QuotaTopN                int           `config:"quota_top_n"`
+	FilesetUsage             bool          `config:"fileset_usage"`
+	FilesetLifecycle         bool          `config:"fileset_lifecycle"`
+	CapacitySplit            bool          `config:"capacity_split"`
+
+	// settings of the optional subsystems
+	Resolver         ResolverConfig     `config:"resolver"`
+	QuotaChangesOnly QuotaChangesConfig `config:"quota_changes_only"`
+	Forecast         ForecastConfig     `config:"forecast"`
+	Alerts           AlertsConfig       `config:"alerts"`
+	Notify           NotifyConfig       `config:"notify"`
+	InodeSpaces      InodeSpacesConfig  `config:"inode_spaces"`
+	Metadata         MetadataConfig     `config:"metadata"`
+	NSDBalance       NSDBalanceConfig   `config:"nsd_balance"`
+}
+
+// NSDBalanceConfig describes when an NSD is considered to be out of balance with the rest of its storage pool
+type NSDBalanceConfig struct {
+	Enabled   bool    `config:"enabled"`
+	Threshold float64 `config:"threshold"` // in percentage points of free space, relative to the pool mean
+}
+
+// MetadataConfig points to the file mapping filesets to their project, PI, department, cost center and expiry date
+type MetadataConfig struct {
+	Enabled bool   `config:"enabled"`
+	Path    string `config:"path"` // a .yml, .yaml, .json or .csv file, reloaded when it changes
+}
+
+// InodeSpacesConfig describes when the inode allocation of an inode space is considered close to its maximum
+type InodeSpacesConfig struct {
+	Enabled           bool    `config:"enabled"`
+	NearMaxPercentage float64 `config:"near_max_percentage"` // allocated inodes as a percentage of maxInodes
+}
+
+// NotifyConfig describes how users are told about their quota
+type NotifyConfig struct {
+	Enabled      bool                            `config:"enabled"`
+	Kinds        []string                        `config:"kinds"`         // the quota types to notify about
+	GraceWarning time.Duration                   `config:"grace_warning"` // warn when the grace period ends within this time
+	Templates    map[string]NotifyTemplateConfig `config:"templates"`     // per transition: over_soft, grace_expiring, grace_expired, over_hard
+	SMTP         SMTPConfig                      `config:"smtp"`
+	Webhook      WebhookConfig                   `config:"webhook"`
+}
+
+// NotifyTemplateConfig contains the text/template for the subject and body of a notification
+type NotifyTemplateConfig struct {
+	Subject string `config:"subject"`
+	Body    string `config:"body"`
+}
+
+// SMTPConfig describes how to send notifications by mail
+type SMTPConfig struct {
+	Enabled  bool   `config:"enabled"`
+	Host     string `config:"host"`
+	Port     int    `config:"port"`
+	Username string `config:"username"`
+	Password string `config:"password"`
+	From     string `config:"from"`
+	To       string `config:"to"` // a text/template, e.g., {{.EntityName}}@example.com
+}
+
+// WebhookConfig describes where to post notifications as JSON
+type WebhookConfig struct {
+	Enabled bool          `config:"enabled"`
+	URL     string        `config:"url"`
+	Timeout time.Duration `config:"timeout"`
+}
+
+// AlertsConfig contains the alert rules that are evaluated on the published events
+type AlertsConfig struct {
+	Enabled bool              `config:"enabled"`
+	Rules   []AlertRuleConfig `config:"rules"`
+}
+
+// AlertRuleConfig describes a single threshold rule, e.g., the free_blocks_percentage of mmdf pooltotal events < 10
+type AlertRuleConfig struct {
+	Name       string            `config:"name"`
+	Severity   string            `config:"severity"`
+	Event      string            `config:"event"` // the key the information is published under, e.g., mmdf or quota
+	Field      string            `config:"field"`
+	Match      map[string]string `config:"match"` // only consider events with these field values
+	Operator   string            `config:"operator"`
+	Value      interface{}       `config:"value"`
+	GroupBy    []string          `config:"group_by"`   // the fields identifying the instance, e.g., device and pool_name
+	Hysteresis float64           `config:"hysteresis"` // how far a numeric value must move back before the alert resolves
+	For        time.Duration     `config:"for"`        // how long the condition must hold before alerting
+}
+
+// ForecastConfig describes the window of usage samples used to forecast the capacity
+type ForecastConfig struct {
+	Enabled    bool          `config:"enabled"`
+	Window     time.Duration `config:"window"`
+	MinSamples int           `config:"min_samples"`
+	MaxSamples int           `config:"max_samples" validate:"min=2"`
+}
+
+// QuotaChangesConfig describes when a quota entry is considered to have changed
+type QuotaChangesConfig struct {
+	Enabled    bool          `config:"enabled"`
+	BlockDelta int64         `config:"block_delta"` // in KiB, as reported by mmrepquota
+	FilesDelta int64         `config:"files_delta"`
+	Heartbeat  time.Duration `config:"heartbeat"` // interval at which a full snapshot is sent regardless
+}
+
+// ResolverConfig describes how user and group IDs in quota entries are mapped to names
+type ResolverConfig struct {
+	Enabled       bool          `config:"enabled"`
+	Sources       []string      `config:"sources"` // nss, file and/or ldap, tried in this order
+	TTL           time.Duration `config:"ttl"`
+	GetentCommand string        `config:"getent"`
+	MappingFile   string        `config:"mapping_file"`
+	LDAP          LDAPConfig    `config:"ldap"`
+}
+
+// LDAPConfig contains the settings to look up posixAccount and posixGroup entries
+type LDAPConfig struct {
+	URI               string `config:"uri"`
+	BaseDN            string `config:"base_dn"`
+	BindDN            string `config:"bind_dn"`
+	BindPassword      string `config:"bind_password"`
+	LdapSearchCommand string `config:"ldapsearch"`
 }
 
 // DefaultConfig should be overridden
 var DefaultConfig = Config{
-	Period:             1 * time.Second,
-	Devices:            []string{"all"},
-	MMRepQuotaCommand:  "mmrepquota",
-	MMLsFsCommand:      "mmlsfs",
-	MMDfCommand:        "mmdf",
-	MMLsFilesetCommand: "mmlsfileset",
+	Period:                   1 * time.Second,
+	Devices:                  []string{"all"},
+	MMRepQuotaCommand:        "mmrepquota",
+	MMLsFsCommand:            "mmlsfs",
+	MMDfCommand:              "mmdf",
+	MMLsFilesetCommand:       "mmlsfileset",
+	MMLsFilesetAFM:           false,
+	GNR:                      false,
+	MMVdiskCommand:           "mmvdisk",
+	MMLsRecoveryGroupCommand: "mmlsrecoverygroup",
+	QoS:                      false,
+	QoSSeconds:               60,
+	MMLsQosCommand:           "mmlsqos",
+	MmFsLog:                  false,
+	MmFsLogPath:              "/var/adm/ras/mmfs.log.latest",
+	Audit:                    false,
+	AuditPaths:               []string{},
+	QuotaDefaults:            false,
+	QuotaLimitChanges:        false,
+	QuotaSummary:             false,
+	QuotaTopN:                0,
+	FilesetUsage:             false,
+	FilesetLifecycle:         false,
+	CapacitySplit:            false,
+	Resolver: ResolverConfig{
+		Enabled:       false,
+		Sources:       []string{"nss"},
+		TTL:           1 * time.Hour,
+		GetentCommand: "getent",
+		LDAP: LDAPConfig{
+			URI:               "ldap://localhost",
+			LdapSearchCommand: "ldapsearch",
+		},
+	},
+	QuotaChangesOnly: QuotaChangesConfig{
+		Enabled:    false,
+		BlockDelta: 1024,
+		FilesDelta: 100,
+		Heartbeat:  24 * time.Hour,
+	},
+	Forecast: ForecastConfig{
+		Enabled:    false,
+		Window:     7 * 24 * time.Hour,
+		MinSamples: 10,
+		MaxSamples: 1000,
+	},
+	Alerts: AlertsConfig{
+		Enabled: false,
+	},
+	Notify: NotifyConfig{
+		Enabled:      false,
+		Kinds:        []string{"USR"},
+		GraceWarning: 3 * 24 * time.Hour,
+		Templates: map[string]NotifyTemplateConfig{
+			"over_soft": {
+				Subject: "Your quota on {{.Filesystem}}/{{.Fileset}} is over its soft limit",
+				Body:    "Dear {{.EntityName}},\n\nyou are over your soft quota limit on {{.Filesystem}}/{{.Fileset}}: you use {{.BlockUsage}} KiB (soft limit {{.BlockSoft}} KiB) in {{.FilesUsage}} files (soft limit {{.FilesSoft}}).\nPlease clean up before the grace period ends.\n",
+			},
+			"grace_expiring": {
+				Subject: "Your grace period on {{.Filesystem}}/{{.Fileset}} ends soon",
+				Body:    "Dear {{.EntityName}},\n\nyour grace period on {{.Filesystem}}/{{.Fileset}} ends around {{.GraceExpires.Format \"2006-01-02 15:04\"}}. After that, you can no longer write data until your usage is below the soft limit.\nYou use {{.BlockUsage}} KiB (soft limit {{.BlockSoft}} KiB) in {{.FilesUsage}} files (soft limit {{.FilesSoft}}).\n",
+			},
+			"grace_expired": {
+				Subject: "Your grace period on {{.Filesystem}}/{{.Fileset}} has ended",
+				Body:    "Dear {{.EntityName}},\n\nyour grace period on {{.Filesystem}}/{{.Fileset}} has ended, so you can no longer write data until your usage is below the soft limit.\nYou use {{.BlockUsage}} KiB (soft limit {{.BlockSoft}} KiB) in {{.FilesUsage}} files (soft limit {{.FilesSoft}}).\n",
+			},
+			"over_hard": {
+				Subject: "Your quota on {{.Filesystem}}/{{.Fileset}} is full",
+				Body:    "Dear {{.EntityName}},\n\nyou reached your hard quota limit on {{.Filesystem}}/{{.Fileset}}, writes will fail until you clean up.\nYou use {{.BlockUsage}} KiB (hard limit {{.BlockHard}} KiB) in {{.FilesUsage}} files (hard limit {{.FilesHard}}).\n",
+			},
+		},
+		SMTP: SMTPConfig{
+			Host: "localhost",
+			Port: 25,
+		},
+		Webhook: WebhookConfig{
+			Timeout: 10 * time.Second,
+		},
+	},
+	InodeSpaces: InodeSpacesConfig{
+		Enabled:           false,
+		NearMaxPercentage: 90,
+	},
+	Metadata: MetadataConfig{
+		Enabled: false,
+	},
+	NSDBalance: NSDBalanceConfig{
+		Enabled:   false,
+		Threshold: 10,
+	},
 }
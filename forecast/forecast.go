@@ -0,0 +1,105 @@
+// Package forecast estimates when usage reaches a limit, through a linear regression over a window of samples
+package forecast
+
+import (
+	"math"
+	"time"
+)
+
+// Sample is a usage measurement at a given time
+type Sample struct {
+	Time time.Time `json:"time"`
+	Used float64   `json:"used"`
+}
+
+// Window keeps the samples of the last period, spaced at least minInterval apart
+type Window struct {
+	Samples []Sample `json:"samples"`
+}
+
+// Add appends the sample, unless it is too close to the previous one, and drops the samples older than the period.
+// It returns true if the window changed.
+func (w *Window) Add(s Sample, period time.Duration, minInterval time.Duration) bool {
+	if n := len(w.Samples); n > 0 && s.Time.Sub(w.Samples[n-1].Time) < minInterval {
+		return false
+	}
+	w.Samples = append(w.Samples, s)
+
+	cutoff := s.Time.Add(-period)
+	i := 0
+	for i < len(w.Samples) && w.Samples[i].Time.Before(cutoff) {
+		i++
+	}
+	w.Samples = w.Samples[i:]
+	return true
+}
+
+// Fit is the result of a least squares linear regression of usage over time
+type Fit struct {
+	Rate     float64 // usage change per second
+	Offset   float64 // usage at the time of the last sample, according to the fit
+	RSquared float64 // coefficient of determination, used as the confidence in the fit
+	Last     time.Time
+}
+
+// Fit does a linear regression over the samples, it needs at least two samples at different times
+func (w *Window) Fit() (Fit, bool) {
+	n := float64(len(w.Samples))
+	if n < 2 {
+		return Fit{}, false
+	}
+
+	first := w.Samples[0].Time
+	var sumX, sumY float64
+	for _, s := range w.Samples {
+		sumX += s.Time.Sub(first).Seconds()
+		sumY += s.Used
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy, syy float64
+	for _, s := range w.Samples {
+		dx := s.Time.Sub(first).Seconds() - meanX
+		dy := s.Used - meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return Fit{}, false
+	}
+
+	rate := sxy / sxx
+	last := w.Samples[len(w.Samples)-1].Time
+	offset := meanY + rate*(last.Sub(first).Seconds()-meanX)
+
+	r2 := 1.0 // a flat line is a perfect fit
+	if syy > 0 {
+		r2 = (sxy * sxy) / (sxx * syy)
+	}
+
+	return Fit{Rate: rate, Offset: offset, RSquared: r2, Last: last}, true
+}
+
+// RatePerDay returns the usage change per day
+func (f Fit) RatePerDay() float64 {
+	return f.Rate * 24 * 3600
+}
+
+// Until returns the time left until the fitted usage reaches the limit, and false if it never will
+func (f Fit) Until(limit float64) (time.Duration, bool) {
+	if limit <= 0 {
+		return 0, false
+	}
+	if f.Offset >= limit {
+		return 0, true
+	}
+	if f.Rate <= 0 {
+		return 0, false
+	}
+	seconds := (limit - f.Offset) / f.Rate
+	if seconds > math.MaxInt64/float64(time.Second) {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
@@ -0,0 +1,72 @@
+//go:build !integration
+
+package forecast
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWindowAdd(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	var w Window
+	for i := 0; i < 5; i++ {
+		if !w.Add(Sample{Time: start.Add(time.Duration(i) * time.Hour), Used: float64(i)}, 3*time.Hour, time.Hour) {
+			t.Errorf("expected sample %d to be added", i)
+		}
+	}
+	if w.Add(Sample{Time: start.Add(4*time.Hour + time.Minute), Used: 10}, 3*time.Hour, time.Hour) {
+		t.Error("expected a sample within the minimum interval to be skipped")
+	}
+
+	// the samples older than the period are gone
+	if len(w.Samples) != 4 || !w.Samples[0].Time.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected the samples from 1h to 4h, got %+v", w.Samples)
+	}
+}
+
+func TestFit(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	// 1000 bytes per hour, starting at 10000
+	var w Window
+	for i := 0; i < 10; i++ {
+		w.Add(Sample{Time: start.Add(time.Duration(i) * time.Hour), Used: 10000 + 1000*float64(i)}, 24*time.Hour, 0)
+	}
+	fit, ok := w.Fit()
+	if !ok {
+		t.Fatal("expected a fit")
+	}
+	if math.Abs(fit.RatePerDay()-24000) > 1e-6 || math.Abs(fit.Offset-19000) > 1e-6 || math.Abs(fit.RSquared-1) > 1e-9 {
+		t.Errorf("unexpected fit %+v", fit)
+	}
+	if d, ok := fit.Until(20000); !ok || d != time.Hour {
+		t.Errorf("expected to reach 20000 in an hour, got %s (%t)", d, ok)
+	}
+	if d, ok := fit.Until(15000); !ok || d != 0 {
+		t.Errorf("expected a limit below the usage to be reached already, got %s (%t)", d, ok)
+	}
+	if _, ok := fit.Until(0); ok {
+		t.Error("there is nothing to reach without a limit")
+	}
+
+	// noisy usage gives a lower confidence, and a shrinking usage never reaches the limit
+	w = Window{}
+	for i, used := range []float64{5000, 4000, 4800, 3000, 3500, 2000} {
+		w.Add(Sample{Time: start.Add(time.Duration(i) * time.Hour), Used: used}, 24*time.Hour, 0)
+	}
+	fit, ok = w.Fit()
+	if !ok || fit.Rate >= 0 || fit.RSquared <= 0 || fit.RSquared >= 1 {
+		t.Errorf("unexpected fit for noisy, shrinking usage %+v", fit)
+	}
+	if _, ok := fit.Until(10000); ok {
+		t.Error("a shrinking usage should never reach the limit")
+	}
+
+	// we need samples at different times
+	w = Window{Samples: []Sample{{Time: start, Used: 1}, {Time: start, Used: 2}}}
+	if _, ok := w.Fit(); ok {
+		t.Error("expected no fit without a time range")
+	}
+}
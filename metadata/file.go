@@ -0,0 +1,108 @@
+package metadata
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// mappingFile is the layout of the YAML and JSON mapping files
+type mappingFile struct {
+	Filesets []Entry `config:"filesets" json:"filesets"`
+}
+
+// readEntries reads the mapping file in the format matching its extension
+func readEntries(path string) ([]Entry, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		return readYAML(path)
+	case ".json":
+		return readJSON(path)
+	case ".csv":
+		return readCSV(path)
+	}
+	return nil, fmt.Errorf("unknown format of metadata mapping file %s, expected .yml, .yaml, .json or .csv", path)
+}
+
+// readYAML reads a file of the form `filesets: [{filesystem: gpfs1, fileset: projA, project: ...}, ...]`
+func readYAML(path string) ([]Entry, error) {
+	cfg, err := common.LoadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load metadata mapping file %s: %v", path, err)
+	}
+	var f mappingFile
+	if err := cfg.Unpack(&f); err != nil {
+		return nil, fmt.Errorf("cannot parse metadata mapping file %s: %v", path, err)
+	}
+	return f.Filesets, nil
+}
+
+// readJSON reads a file with the same layout as the YAML file
+func readJSON(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open metadata mapping file: %v", err)
+	}
+	var f mappingFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cannot parse metadata mapping file %s: %v", path, err)
+	}
+	return f.Filesets, nil
+}
+
+// readCSV reads a file with a header line naming the columns, using the same names as the YAML file, e.g.,
+// `filesystem,fileset,path_prefix,project,pi,department,cost_center,expires`
+func readCSV(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open metadata mapping file: %v", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the header of metadata mapping file %s: %v", path, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse metadata mapping file %s: %v", path, err)
+		}
+		entries = append(entries, Entry{
+			Filesystem: column(record, "filesystem"),
+			Fileset:    column(record, "fileset"),
+			PathPrefix: column(record, "path_prefix"),
+			Project:    column(record, "project"),
+			PI:         column(record, "pi"),
+			Department: column(record, "department"),
+			CostCenter: column(record, "cost_center"),
+			Expires:    column(record, "expires"),
+		})
+	}
+	return entries, nil
+}
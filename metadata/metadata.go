@@ -0,0 +1,179 @@
+// Package metadata attaches site specific information, such as the owning project or cost center, to filesets
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// expiresLayout is the date format of the expiry date in the mapping file
+const expiresLayout = "2006-01-02"
+
+// Entry contains the metadata of a fileset. It is matched on the filesystem and fileset name, or on the junction path
+// prefix when no fileset name is given. An empty filesystem matches all filesystems.
+type Entry struct {
+	Filesystem string `config:"filesystem" json:"filesystem"`
+	Fileset    string `config:"fileset" json:"fileset"`
+	PathPrefix string `config:"path_prefix" json:"path_prefix"`
+	Project    string `config:"project" json:"project"`
+	PI         string `config:"pi" json:"pi"`
+	Department string `config:"department" json:"department"`
+	CostCenter string `config:"cost_center" json:"cost_center"`
+	Expires    string `config:"expires" json:"expires"` // YYYY-MM-DD, empty if the project does not expire
+}
+
+// ToMapStr turns the metadata into a common.MapStr, expired is determined relative to now
+func (e *Entry) ToMapStr(now time.Time) common.MapStr {
+	m := common.MapStr{
+		"project":     e.Project,
+		"pi":          e.PI,
+		"department":  e.Department,
+		"cost_center": e.CostCenter,
+	}
+	if expires, err := time.ParseInLocation(expiresLayout, e.Expires, time.Local); err == nil {
+		m["expires"] = expires
+		m["expired"] = now.After(expires)
+	}
+	return m
+}
+
+// validate checks that the entry can be matched and has a sensible expiry date
+func (e *Entry) validate() error {
+	if e.Fileset == "" && e.PathPrefix == "" {
+		return fmt.Errorf("entry for project %q has neither a fileset nor a path_prefix", e.Project)
+	}
+	if e.Expires != "" {
+		if _, err := time.Parse(expiresLayout, e.Expires); err != nil {
+			return fmt.Errorf("entry for project %q has an invalid expires date %q", e.Project, e.Expires)
+		}
+	}
+	e.PathPrefix = strings.TrimSuffix(e.PathPrefix, "/")
+	return nil
+}
+
+// matchesPath returns true if the path is the prefix or lies below it
+func (e *Entry) matchesPath(path string) bool {
+	return e.PathPrefix != "" && (path == e.PathPrefix || strings.HasPrefix(path, e.PathPrefix+"/"))
+}
+
+// mapping holds the entries of a single version of the mapping file
+type mapping struct {
+	byFileset map[[2]string]Entry
+	byPath    []Entry // longest prefix first
+}
+
+func newMapping(entries []Entry) (*mapping, error) {
+	m := &mapping{byFileset: make(map[[2]string]Entry)}
+	for _, e := range entries {
+		if err := e.validate(); err != nil {
+			return nil, err
+		}
+		if e.Fileset != "" {
+			m.byFileset[[2]string{e.Filesystem, e.Fileset}] = e
+		} else {
+			m.byPath = append(m.byPath, e)
+		}
+	}
+	sort.SliceStable(m.byPath, func(i, j int) bool {
+		return len(m.byPath[i].PathPrefix) > len(m.byPath[j].PathPrefix)
+	})
+	return m, nil
+}
+
+func (m *mapping) lookup(filesystem string, fileset string, path string) (Entry, bool) {
+	if e, ok := m.byFileset[[2]string{filesystem, fileset}]; ok {
+		return e, true
+	}
+	if e, ok := m.byFileset[[2]string{"", fileset}]; ok {
+		return e, true
+	}
+	if path == "" {
+		return Entry{}, false
+	}
+	for _, e := range m.byPath {
+		if (e.Filesystem == "" || e.Filesystem == filesystem) && e.matchesPath(path) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Source keeps the mapping file loaded, and reloads it when it changes
+type Source struct {
+	path string
+
+	mutex   sync.RWMutex
+	mapping *mapping
+	modTime time.Time
+	size    int64
+}
+
+// New loads the mapping file, which can be in YAML, JSON or CSV format depending on its extension
+func New(path string) (*Source, error) {
+	s := &Source{path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open metadata mapping file: %v", err)
+	}
+	if err := s.load(info); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the mapping file and replaces the current mapping
+func (s *Source) load(info os.FileInfo) error {
+	entries, err := readEntries(s.path)
+	if err != nil {
+		return err
+	}
+	m, err := newMapping(entries)
+	if err != nil {
+		return fmt.Errorf("invalid metadata mapping file %s: %v", s.path, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.mapping = m
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	return nil
+}
+
+// Reload reads the mapping file again if it was modified since it was last loaded. When the new version cannot be
+// loaded, we keep using the previous one.
+func (s *Source) Reload() {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		logp.Err("Cannot check metadata mapping file %s, keeping the current mapping. Error: %s", s.path, err)
+		return
+	}
+
+	s.mutex.RLock()
+	changed := !info.ModTime().Equal(s.modTime) || info.Size() != s.size
+	s.mutex.RUnlock()
+	if !changed {
+		return
+	}
+
+	if err := s.load(info); err != nil {
+		logp.Err("Cannot reload metadata mapping file, keeping the current mapping. Error: %s", err)
+		return
+	}
+	logp.Info("Reloaded metadata mapping file %s", s.path)
+}
+
+// Lookup returns the metadata for the fileset. An entry for the filesystem and fileset name takes precedence over
+// one for the junction path, and the longest matching path prefix wins.
+func (s *Source) Lookup(filesystem string, fileset string, path string) (Entry, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.mapping.lookup(filesystem, fileset, path)
+}
@@ -0,0 +1,122 @@
+//go:build !integration
+
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeMapping writes a mapping file and gives it a distinct modification time, so Reload notices the change
+func writeMapping(t *testing.T, path string, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const testCSV = `# site metadata
+filesystem,fileset,path_prefix,project,pi,department,cost_center,expires
+fs1,projA,,Project A,alice,physics,CC100,2020-01-31
+,projA,,Any project A,bob,chemistry,CC200,
+,,/gpfs/fs1/projects,Projects,carol,it,CC300,
+fs1,,/gpfs/fs1/projects/big/,Big project,dave,biology,CC400,2999-12-31
+fs2,,/gpfs/fs2,Scratch,erin,it,CC500,
+`
+
+func TestLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.csv")
+	writeMapping(t, path, testCSV, time.Now().Add(-time.Hour))
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filesystem string
+		fileset    string
+		path       string
+		project    string
+	}{
+		{"fs1", "projA", "/gpfs/fs1/projects/big", "Project A"},     // the filesystem and fileset go first
+		{"fs2", "projA", "", "Any project A"},                       // then the fileset on any filesystem
+		{"fs1", "big", "/gpfs/fs1/projects/big/sub", "Big project"}, // then the longest path prefix
+		{"fs1", "small", "/gpfs/fs1/projects/small", "Projects"},    // on any filesystem
+		{"fs1", "bigger", "/gpfs/fs1/projects/bigger", "Projects"},  // only below the prefix itself
+		{"fs1", "scratch", "/gpfs/fs2/scratch", ""},                 // on the given filesystem only
+		{"fs2", "scratch", "/gpfs/fs2", "Scratch"},                  // the prefix itself
+		{"fs1", "home", "", ""},                                     // no path to match on
+	}
+	for _, test := range tests {
+		e, ok := s.Lookup(test.filesystem, test.fileset, test.path)
+		if ok != (test.project != "") || e.Project != test.project {
+			t.Errorf("%s/%s at %q: expected %q, got %q (%t)", test.filesystem, test.fileset, test.path, test.project, e.Project, ok)
+		}
+	}
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local)
+	e, _ := s.Lookup("fs1", "projA", "")
+	m := e.ToMapStr(now)
+	if m["expired"] != true || m["cost_center"] != "CC100" || m["pi"] != "alice" {
+		t.Errorf("unexpected metadata %v", map[string]interface{}(m))
+	}
+	e, _ = s.Lookup("fs2", "projA", "")
+	if _, ok := e.ToMapStr(now)["expired"]; ok {
+		t.Error("a project without an expiry date cannot expire")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	start := time.Now().Add(-time.Hour)
+	writeMapping(t, path, `{"filesets": [{"filesystem": "fs1", "fileset": "projA", "project": "Project A"}]}`, start)
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := s.Lookup("fs1", "projA", ""); !ok || e.Project != "Project A" {
+		t.Fatalf("expected Project A, got %q (%t)", e.Project, ok)
+	}
+
+	writeMapping(t, path, `{"filesets": [{"filesystem": "fs1", "fileset": "projA", "project": "Renamed"}]}`, start.Add(time.Minute))
+	s.Reload()
+	if e, _ := s.Lookup("fs1", "projA", ""); e.Project != "Renamed" {
+		t.Errorf("expected the changed mapping to be reloaded, got %q", e.Project)
+	}
+
+	// an invalid version does not replace the current mapping
+	writeMapping(t, path, `{"filesets": [{"filesystem": "fs1", "project": "No fileset"}]}`, start.Add(2*time.Minute))
+	s.Reload()
+	if e, _ := s.Lookup("fs1", "projA", ""); e.Project != "Renamed" {
+		t.Errorf("expected to keep the previous mapping, got %q", e.Project)
+	}
+	os.Remove(path)
+	s.Reload()
+	if e, _ := s.Lookup("fs1", "projA", ""); e.Project != "Renamed" {
+		t.Errorf("expected to keep the previous mapping, got %q", e.Project)
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"metadata.txt":     "",
+		"invalid.csv":      "filesystem,fileset,project,expires\nfs1,projA,Project A,31/01/2020\n",
+		"unmatchable.json": `{"filesets": [{"project": "Nothing"}]}`,
+		"broken.json":      `{"filesets": [`,
+	} {
+		path := filepath.Join(dir, name)
+		writeMapping(t, path, content, time.Now())
+		if _, err := New(path); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+	if _, err := New(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,254 @@
+// Package notify tells users about quota state transitions, by mail or through a webhook
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/hpcugent/gpfsbeat/config"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+// Transitions we notify about
+const (
+	OverSoft      = "over_soft"
+	GraceExpiring = "grace_expiring"
+	GraceExpired  = "grace_expired"
+	OverHard      = "over_hard"
+)
+
+// registryPrefix prefixes the keys of the last notified transition in the registry
+const registryPrefix = "notify::"
+
+// state is what we keep in the registry for each quota entry: the last transition and the senders that delivered it
+type state struct {
+	Transition string          `json:"transition"`
+	Delivered  map[string]bool `json:"delivered"`
+}
+
+// Message is the data available to the templates
+type Message struct {
+	Transition     string
+	Filesystem     string
+	Fileset        string
+	Kind           string
+	EntityID       string
+	EntityName     string
+	State          string
+	BlockUsage     int64
+	BlockSoft      int64
+	BlockHard      int64
+	FilesUsage     int64
+	FilesSoft      int64
+	FilesHard      int64
+	GraceRemaining time.Duration
+	GraceExpires   time.Time
+	Subject        string
+	Body           string
+}
+
+// sender delivers a rendered message
+type sender interface {
+	Name() string
+	Send(m *Message) error
+}
+
+// templates holds the parsed subject and body templates per transition
+type templates struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// Notifier turns quota entries into messages, at most once per transition of each entry
+type Notifier struct {
+	config    config.NotifyConfig
+	store     *statestore.Store
+	senders   []sender
+	templates map[string]templates
+	kinds     map[string]bool
+}
+
+// New sets up the notifier, the store keeps track of what we already notified
+func New(c config.NotifyConfig, store *statestore.Store) (*Notifier, error) {
+	n := &Notifier{
+		config:    c,
+		store:     store,
+		templates: make(map[string]templates),
+		kinds:     make(map[string]bool),
+	}
+	for _, k := range c.Kinds {
+		n.kinds[k] = true
+	}
+
+	for _, transition := range []string{OverSoft, GraceExpiring, GraceExpired, OverHard} {
+		t, ok := c.Templates[transition]
+		if !ok {
+			return nil, fmt.Errorf("no notification template for %s", transition)
+		}
+		subject, err := template.New(transition + " subject").Parse(t.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the %s subject template: %v", transition, err)
+		}
+		body, err := template.New(transition + " body").Parse(t.Body)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the %s body template: %v", transition, err)
+		}
+		n.templates[transition] = templates{subject: subject, body: body}
+	}
+
+	if c.SMTP.Enabled {
+		s, err := newSMTPSender(c.SMTP)
+		if err != nil {
+			return nil, err
+		}
+		n.senders = append(n.senders, s)
+	}
+	if c.Webhook.Enabled {
+		n.senders = append(n.senders, newWebhookSender(c.Webhook))
+	}
+	if len(n.senders) == 0 {
+		return nil, fmt.Errorf("notifications need smtp and/or webhook to be enabled")
+	}
+	return n, nil
+}
+
+// transition determines what we should tell about the quota entry, if anything
+func (n *Notifier) transition(q *parser.QuotaInfo) (string, parser.QuotaGrace) {
+	grace := q.BlockGrace()
+	if q.FilesState() == parser.QuotaStateInGrace && (q.BlockState() != parser.QuotaStateInGrace || q.FilesGrace().Remaining() < grace.Remaining()) {
+		grace = q.FilesGrace()
+	}
+
+	switch q.State() {
+	case parser.QuotaStateOverHard:
+		return OverHard, grace
+	case parser.QuotaStateInGrace:
+		if grace.Remaining() <= n.config.GraceWarning {
+			return GraceExpiring, grace
+		}
+		return OverSoft, grace
+	case parser.QuotaStateGraceExpired:
+		return GraceExpired, grace
+	case parser.QuotaStateOverSoft:
+		return OverSoft, grace
+	}
+	return "", grace
+}
+
+// message renders the templates for the quota entry
+func (n *Notifier) message(transition string, q *parser.QuotaInfo, grace parser.QuotaGrace, now time.Time) (*Message, error) {
+	limits := q.Limits()
+	m := &Message{
+		Transition: transition,
+		Filesystem: q.Filesystem(),
+		Fileset:    q.Fileset(),
+		Kind:       q.Kind(),
+		EntityID:   q.EntityID(),
+		EntityName: q.EntityName(),
+		State:      q.State(),
+		BlockUsage: q.BlockUsage(),
+		BlockSoft:  limits.BlockSoft,
+		BlockHard:  limits.BlockHard,
+		FilesUsage: q.FilesUsage(),
+		FilesSoft:  limits.FilesSoft,
+		FilesHard:  limits.FilesHard,
+	}
+	if grace.State() == parser.GraceStateInGrace {
+		m.GraceRemaining = grace.Remaining()
+		m.GraceExpires = now.Add(grace.Remaining())
+	}
+
+	t := n.templates[transition]
+	var subject, body bytes.Buffer
+	if err := t.subject.Execute(&subject, m); err != nil {
+		return nil, err
+	}
+	if err := t.body.Execute(&body, m); err != nil {
+		return nil, err
+	}
+	m.Subject = strings.TrimSpace(subject.String())
+	m.Body = body.String()
+	return m, nil
+}
+
+// key returns the registry key for the quota entry
+func key(k parser.QuotaKey) string {
+	return registryPrefix + strings.Join([]string{k.Filesystem, k.Fileset, k.Kind, k.Entity}, "/")
+}
+
+// Process sends the notifications for the quota entries whose transition we did not notify yet, and returns
+// the number of notifications that reached all senders. A sender that fails is tried again during the next run,
+// without repeating the notification through the senders that already delivered it.
+func (n *Notifier) Process(quotas []parser.QuotaInfo, now time.Time) int {
+	sent := 0
+	for i := range quotas {
+		q := &quotas[i]
+		if !n.kinds[q.Kind()] {
+			continue
+		}
+		k := key(q.Key())
+		transition, grace := n.transition(q)
+
+		var notified state
+		known, err := n.store.Has(k)
+		if err == nil && known {
+			err = n.store.Get(k, &notified)
+		}
+		if err != nil {
+			logp.Err("Cannot read the notification state for %s. Error: %s", k, err)
+			continue
+		}
+
+		if transition == "" {
+			// back within quota, so we notify again on the next transition
+			if known {
+				if err := n.store.Remove(k); err != nil {
+					logp.Err("Cannot remove the notification state for %s. Error: %s", k, err)
+				}
+			}
+			continue
+		}
+		if transition != notified.Transition || notified.Delivered == nil {
+			notified = state{Transition: transition, Delivered: make(map[string]bool)}
+		}
+		var pending []sender
+		for _, s := range n.senders {
+			if !notified.Delivered[s.Name()] {
+				pending = append(pending, s)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		m, err := n.message(transition, q, grace, now)
+		if err != nil {
+			logp.Err("Cannot render the %s notification for %s. Error: %s", transition, k, err)
+			continue
+		}
+		delivered := 0
+		for _, s := range pending {
+			if err := s.Send(m); err != nil {
+				logp.Err("Cannot send the %s notification for %s through %s. Error: %s", transition, k, s.Name(), err)
+				continue // try again during the next run
+			}
+			notified.Delivered[s.Name()] = true
+			delivered++
+		}
+		if delivered == 0 {
+			continue
+		}
+		if delivered == len(pending) {
+			sent++
+		}
+		if err := n.store.Set(k, notified); err != nil {
+			logp.Err("Cannot store the notification state for %s. Error: %s", k, err)
+		}
+	}
+	return sent
+}
@@ -0,0 +1,266 @@
+//go:build !integration
+
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend/memlog"
+	"github.com/hpcugent/gpfsbeat/config"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+// newTestStore returns an empty registry store in a temporary directory
+func newTestStore(t *testing.T) *statestore.Store {
+	t.Helper()
+	backend, err := memlog.New(logp.NewLogger("test"), memlog.Settings{Root: t.TempDir(), FileMode: 0600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := statestore.NewRegistry(backend).Get("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// testQuota parses a single USR and GRP quota entry for alice with the given block usage and grace
+func testQuota(t *testing.T, blockUsage int, blockGrace string) []parser.QuotaInfo {
+	t.Helper()
+	output := "mmrepquota::HEADER:version:reserved:reserved:filesystemName:quotaType:id:name:blockUsage:blockQuota:blockLimit:blockInDoubt:blockGrace:filesUsage:filesQuota:filesLimit:filesInDoubt:filesGrace:remarks:quota:defQuota:fid:filesetname:\n" +
+		"mmrepquota::0:1:::fs1:USR:1000:alice:" + strconv.Itoa(blockUsage) + ":500:1000:0:" + blockGrace + ":10:0:0:0:none:e:on:on:1:data:\n" +
+		"mmrepquota::0:1:::fs1:GRP:100:users:" + strconv.Itoa(blockUsage) + ":500:1000:0:" + blockGrace + ":10:0:0:0:none:e:on:on:1:data:\n"
+	quotas, err := parser.ParseMmRepQuota(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return quotas
+}
+
+// smtpServer accepts mails on a local port and keeps the recipients and messages
+type smtpServer struct {
+	listener net.Listener
+
+	mutex      sync.Mutex
+	recipients []string
+	messages   []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{listener: l}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// serve speaks just enough SMTP for net/smtp.SendMail
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.mutex.Lock()
+			s.recipients = append(s.recipients, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mutex.Unlock()
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mutex.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mutex.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default: // EHLO, MAIL FROM, ...
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) received() ([]string, []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.recipients...), append([]string{}, s.messages...)
+}
+
+func TestNotifier(t *testing.T) {
+	mails := newSMTPServer(t)
+
+	var mutex sync.Mutex
+	var payloads []map[string]interface{}
+	failing := true
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("cannot decode the webhook payload: %v", err)
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		payloads = append(payloads, payload)
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer webhook.Close()
+
+	c := config.DefaultConfig.Notify
+	c.SMTP = config.SMTPConfig{Enabled: true, Host: "127.0.0.1", Port: mails.port(), From: "gpfs@example.com", To: "{{.EntityName}}@example.com"}
+	c.Webhook = config.WebhookConfig{Enabled: true, URL: webhook.URL, Timeout: 5 * time.Second}
+	n, err := New(c, newTestStore(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	// the webhook fails, so only the webhook is tried again the next time
+	if sent := n.Process(testQuota(t, 600, "6%20days"), now); sent != 0 {
+		t.Errorf("expected no completely delivered notifications while the webhook fails, got %d", sent)
+	}
+	if _, messages := mails.received(); len(messages) != 1 {
+		t.Fatalf("expected the mail to go out while the webhook fails, got %d mails", len(messages))
+	}
+	mutex.Lock()
+	failing = false
+	mutex.Unlock()
+
+	steps := []struct {
+		blockUsage int
+		grace      string
+		sent       int
+	}{
+		{600, "6%20days", 1}, // over the soft limit
+		{650, "5%20days", 0}, // already notified
+		{650, "2%20days", 1}, // the grace period ends soon
+		{1000, "1%20day", 1}, // over the hard limit
+		{1000, "expired", 0}, // still over the hard limit
+		{100, "none", 0},     // back within quota
+		{600, "6%20days", 1}, // over the soft limit again
+	}
+	for i, step := range steps {
+		if sent := n.Process(testQuota(t, step.blockUsage, step.grace), now); sent != step.sent {
+			t.Errorf("step %d: expected %d notifications, got %d", i, step.sent, sent)
+		}
+	}
+
+	recipients, messages := mails.received()
+	if len(messages) != 4 {
+		t.Fatalf("expected 4 mails, got %d", len(messages))
+	}
+	for _, r := range recipients {
+		if r != "alice@example.com" {
+			t.Errorf("unexpected recipient %s", r)
+		}
+	}
+	for i, subject := range []string{"is over its soft limit", "ends soon", "is full", "is over its soft limit"} {
+		if !strings.Contains(messages[i], "Subject: Your ") || !strings.Contains(messages[i], subject) {
+			t.Errorf("mail %d: expected a subject with %q, got %q", i, subject, messages[i])
+		}
+	}
+	if !strings.Contains(messages[1], "You use 650 KiB (soft limit 500 KiB)") {
+		t.Errorf("expected the usage in the body, got %q", messages[1])
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(payloads) != 5 {
+		t.Fatalf("expected 5 webhook calls, got %d", len(payloads))
+	}
+	for i, transition := range []string{OverSoft, OverSoft, GraceExpiring, OverHard, OverSoft} {
+		if payloads[i]["transition"] != transition {
+			t.Errorf("webhook call %d: expected %s, got %v", i, transition, payloads[i]["transition"])
+		}
+	}
+	if p := payloads[2]; p["grace_remaining_seconds"] != float64(2*24*3600) || p["entity"].(map[string]interface{})["name"] != "alice" {
+		t.Errorf("unexpected grace_expiring payload %v", p)
+	}
+}
+
+// recordingSender keeps the transitions it was asked to deliver
+type recordingSender struct {
+	transitions []string
+}
+
+func (r *recordingSender) Name() string { return "recording" }
+
+func (r *recordingSender) Send(m *Message) error {
+	r.transitions = append(r.transitions, m.Transition)
+	return nil
+}
+
+func TestGraceExpired(t *testing.T) {
+	c := config.DefaultConfig.Notify
+	c.Webhook = config.WebhookConfig{Enabled: true, URL: "http://localhost"}
+	n, err := New(c, newTestStore(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &recordingSender{}
+	n.senders = []sender{recorder}
+	now := time.Now()
+
+	for _, grace := range []string{"2%20days", "expired", "expired"} {
+		n.Process(testQuota(t, 650, grace), now)
+	}
+	// the end of the grace period is not announced as a new soft limit overrun
+	expected := []string{GraceExpiring, GraceExpired}
+	if strings.Join(recorder.transitions, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %q, got %q", expected, recorder.transitions)
+	}
+
+	m, err := n.message(GraceExpired, &testQuota(t, 650, "expired")[0], parser.QuotaGrace{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(m.Subject, "has ended") || strings.Contains(m.Body, "before the grace period ends") {
+		t.Errorf("unexpected grace_expired message %q: %q", m.Subject, m.Body)
+	}
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/hpcugent/gpfsbeat/config"
+)
+
+// smtpSender mails the message to the address derived from the entity
+type smtpSender struct {
+	config config.SMTPConfig
+	to     *template.Template
+}
+
+func newSMTPSender(c config.SMTPConfig) (*smtpSender, error) {
+	to, err := template.New("to").Parse(c.To)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the smtp to template: %v", err)
+	}
+	return &smtpSender{config: c, to: to}, nil
+}
+
+// Name identifies the sender in the notification state
+func (s *smtpSender) Name() string {
+	return "smtp"
+}
+
+// Send delivers the message through the configured SMTP server
+func (s *smtpSender) Send(m *Message) error {
+	var to bytes.Buffer
+	if err := s.to.Execute(&to, m); err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(m.Body)
+
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	return smtp.SendMail(addr, auth, s.config.From, []string{to.String()}, msg.Bytes())
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hpcugent/gpfsbeat/config"
+)
+
+// webhookSender posts the message as JSON
+type webhookSender struct {
+	config config.WebhookConfig
+	client *http.Client
+}
+
+func newWebhookSender(c config.WebhookConfig) *webhookSender {
+	return &webhookSender{config: c, client: &http.Client{Timeout: c.Timeout}}
+}
+
+// Name identifies the sender in the notification state
+func (w *webhookSender) Name() string {
+	return "webhook"
+}
+
+// Send posts the message to the configured URL
+func (w *webhookSender) Send(m *Message) error {
+	payload := map[string]interface{}{
+		"transition":  m.Transition,
+		"filesystem":  m.Filesystem,
+		"fileset":     m.Fileset,
+		"kind":        m.Kind,
+		"entity":      map[string]string{"id": m.EntityID, "name": m.EntityName},
+		"state":       m.State,
+		"block_usage": m.BlockUsage,
+		"block_soft":  m.BlockSoft,
+		"block_hard":  m.BlockHard,
+		"files_usage": m.FilesUsage,
+		"files_soft":  m.FilesSoft,
+		"files_hard":  m.FilesHard,
+		"subject":     m.Subject,
+		"body":        m.Body,
+	}
+	if !m.GraceExpires.IsZero() {
+		payload["grace_remaining_seconds"] = int64(m.GraceRemaining.Seconds())
+		payload["grace_expires"] = m.GraceExpires
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.config.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s", w.config.URL, resp.Status)
+	}
+	return nil
+}
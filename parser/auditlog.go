@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// AuditRecord contains a single file audit logging record, as written by GPFS in the audit fileset
+type AuditRecord struct {
+	event         string
+	subEvent      string
+	path          string
+	oldPath       string
+	clusterName   string
+	nodeName      string
+	nfsClientIP   string
+	filesystem    string
+	fileset       string
+	poolName      string
+	inode         int64
+	fileSize      int64
+	ownerUserID   string
+	ownerGroupID  string
+	clientUserID  string
+	clientGroupID string
+	processID     string
+	permissions   string
+	eventTime     time.Time
+}
+
+// auditRecordJSON mirrors the JSON layout of the audit records, GPFS writes nearly all values as strings
+type auditRecordJSON struct {
+	Event         string `json:"event"`
+	SubEvent      string `json:"subEvent"`
+	Path          string `json:"path"`
+	OldPath       string `json:"oldPath"`
+	ClusterName   string `json:"clusterName"`
+	NodeName      string `json:"nodeName"`
+	NfsClientIP   string `json:"nfsClientIp"`
+	FsName        string `json:"fsName"`
+	FilesetName   string `json:"filesetName"`
+	PoolName      string `json:"poolName"`
+	Inode         string `json:"inode"`
+	FileSize      string `json:"fileSize"`
+	OwnerUserID   string `json:"ownerUserId"`
+	OwnerGroupID  string `json:"ownerGroupId"`
+	ClientUserID  string `json:"clientUserId"`
+	ClientGroupID string `json:"clientGroupId"`
+	ProcessID     string `json:"processId"`
+	Permissions   string `json:"permissions"`
+	EventTime     string `json:"eventTime"`
+}
+
+// ToMapStr turns the audit record into a common.MapStr
+func (a *AuditRecord) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"event":           a.event,
+		"sub_event":       a.subEvent,
+		"path":            a.path,
+		"old_path":        a.oldPath,
+		"cluster_name":    a.clusterName,
+		"node_name":       a.nodeName,
+		"nfs_client_ip":   a.nfsClientIP,
+		"filesystem":      a.filesystem,
+		"fileset":         a.fileset,
+		"pool_name":       a.poolName,
+		"inode":           a.inode,
+		"file_size":       a.fileSize,
+		"owner_user_id":   a.ownerUserID,
+		"owner_group_id":  a.ownerGroupID,
+		"client_user_id":  a.clientUserID,
+		"client_group_id": a.clientGroupID,
+		"process_id":      a.processID,
+		"permissions":     a.permissions,
+	}
+}
+
+// UpdateDevice does not do anything, since we already have that information
+func (a *AuditRecord) UpdateDevice(device string) {}
+
+// EventTime returns the time at which the audited event took place
+func (a *AuditRecord) EventTime() time.Time {
+	return a.eventTime
+}
+
+// parseAuditInt parses the numeric values, which may be missing in some event types
+func parseAuditInt(s string) int64 {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// ParseAuditRecord converts a single JSON line from an audit log file into an audit record
+func ParseAuditRecord(line []byte) (*AuditRecord, error) {
+	var r auditRecordJSON
+	if err := json.Unmarshal(line, &r); err != nil {
+		return nil, err
+	}
+
+	eventTime, err := time.Parse("2006-01-02_15:04:05-0700", r.EventTime)
+	if err != nil {
+		eventTime = time.Now()
+	}
+
+	return &AuditRecord{
+		event:         r.Event,
+		subEvent:      r.SubEvent,
+		path:          r.Path,
+		oldPath:       r.OldPath,
+		clusterName:   r.ClusterName,
+		nodeName:      r.NodeName,
+		nfsClientIP:   r.NfsClientIP,
+		filesystem:    r.FsName,
+		fileset:       r.FilesetName,
+		poolName:      r.PoolName,
+		inode:         parseAuditInt(r.Inode),
+		fileSize:      parseAuditInt(r.FileSize),
+		ownerUserID:   r.OwnerUserID,
+		ownerGroupID:  r.OwnerGroupID,
+		clientUserID:  r.ClientUserID,
+		clientGroupID: r.ClientGroupID,
+		processID:     r.ProcessID,
+		permissions:   r.Permissions,
+		eventTime:     eventTime,
+	}, nil
+}
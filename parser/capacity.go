@@ -0,0 +1,61 @@
+package parser
+
+// Kinds of capacity usage
+const (
+	CapacityFileset    = "fileset"
+	CapacityPool       = "pool"
+	CapacityFilesystem = "filesystem"
+)
+
+// CapacityUsage is the block usage of a fileset, storage pool or filesystem in bytes. Limits that are not set are 0.
+type CapacityUsage struct {
+	Kind       string
+	Filesystem string
+	Name       string
+	Used       int64
+	Soft       int64
+	Hard       int64
+	Capacity   int64
+}
+
+// ID uniquely identifies the fileset, pool or filesystem
+func (c CapacityUsage) ID() string {
+	return c.Kind + "/" + c.Filesystem + "/" + c.Name
+}
+
+// CapacityUsage returns the block usage of a FILESET quota entry, mmrepquota reports the values in KiB
+func (q *QuotaInfo) CapacityUsage() (CapacityUsage, bool) {
+	if q.kind != "FILESET" {
+		return CapacityUsage{}, false
+	}
+	return CapacityUsage{
+		Kind:       CapacityFileset,
+		Filesystem: q.filesystem,
+		Name:       q.fileset,
+		Used:       q.blockUsage * 1024,
+		Soft:       q.blockSoft * 1024,
+		Hard:       q.blockHard * 1024,
+	}, true
+}
+
+// CapacityUsage returns the usage of the storage pool, mmdf reports the values in KiB
+func (m *MmDfPoolTotalInfo) CapacityUsage() CapacityUsage {
+	return CapacityUsage{
+		Kind:       CapacityPool,
+		Filesystem: m.device,
+		Name:       m.poolName,
+		Used:       (m.poolSize - m.freeBlocks) * 1024,
+		Capacity:   m.poolSize * 1024,
+	}
+}
+
+// CapacityUsage returns the usage of the filesystem, mmdf reports the values in KiB
+func (m *MmDfFsTotalInfo) CapacityUsage() CapacityUsage {
+	return CapacityUsage{
+		Kind:       CapacityFilesystem,
+		Filesystem: m.device,
+		Name:       m.device,
+		Used:       (m.fsSize - m.freeBlocks) * 1024,
+		Capacity:   m.fsSize * 1024,
+	}
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// nsdCategory holds the capacity of the NSDs of a filesystem with the same metadata and data roles, in KiB
+type nsdCategory struct {
+	nsds          int64
+	size          int64
+	freeBlocks    int64
+	freeFragments int64
+}
+
+// add counts the NSD in the category
+func (c *nsdCategory) add(nsd *MmDfNSDInfo) {
+	c.nsds++
+	c.size += nsd.diskSize
+	c.freeBlocks += nsd.freeBlocks
+	c.freeFragments += nsd.freeFragments
+}
+
+// plus returns the combined capacity of both categories
+func (c nsdCategory) plus(other nsdCategory) nsdCategory {
+	return nsdCategory{
+		nsds:          c.nsds + other.nsds,
+		size:          c.size + other.size,
+		freeBlocks:    c.freeBlocks + other.freeBlocks,
+		freeFragments: c.freeFragments + other.freeFragments,
+	}
+}
+
+// toMapStr returns the capacity in bytes, the free space includes the free fragments since metadata is stored in
+// subblocks
+func (c nsdCategory) toMapStr() common.MapStr {
+	free := c.freeBlocks + c.freeFragments
+	m := common.MapStr{
+		"nsds": c.nsds,
+		"size": c.size * 1024,
+		"free": free * 1024,
+	}
+	if p, ok := percentage(free, c.size); ok {
+		m["free_percentage"] = p
+	}
+	return m
+}
+
+// CapacitySplitInfo separates the capacity of the system pool of a filesystem into metadata-only, data-only and mixed
+// NSDs. Metadata NSDs always belong to the system pool, so the metadata capacity is what remains for the inodes,
+// directories and indirect blocks, regardless of how much room the data pools have left.
+type CapacitySplitInfo struct {
+	device       string
+	metadataOnly nsdCategory
+	dataOnly     nsdCategory
+	mixed        nsdCategory
+}
+
+// ToMapStr turns the capacity split into a common.MapStr. The metadata and data totals both include the mixed NSDs.
+func (c *CapacitySplitInfo) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"device":        c.device,
+		"metadata_only": c.metadataOnly.toMapStr(),
+		"data_only":     c.dataOnly.toMapStr(),
+		"mixed":         c.mixed.toMapStr(),
+		"metadata":      c.metadataOnly.plus(c.mixed).toMapStr(),
+		"data":          c.dataOnly.plus(c.mixed).toMapStr(),
+	}
+}
+
+// UpdateDevice sets the device name
+func (c *CapacitySplitInfo) UpdateDevice(device string) {
+	c.device = device
+}
+
+// SplitCapacity aggregates the nsd records of the system pool in the ParseMmDf output per filesystem according to
+// their metadata and data roles. The NSDs of the other pools only hold data, so they would blur the data totals.
+func SplitCapacity(results []ParseResult) []CapacitySplitInfo {
+	var devices []string
+	splits := make(map[string]*CapacitySplitInfo)
+	for _, r := range results {
+		nsd, ok := r.(*MmDfNSDInfo)
+		if !ok || nsd.storagePool != "system" {
+			continue
+		}
+		s, ok := splits[nsd.device]
+		if !ok {
+			s = &CapacitySplitInfo{device: nsd.device}
+			splits[nsd.device] = s
+			devices = append(devices, nsd.device)
+		}
+		switch {
+		case nsd.metadata && nsd.data:
+			s.mixed.add(nsd)
+		case nsd.metadata:
+			s.metadataOnly.add(nsd)
+		case nsd.data:
+			s.dataOnly.add(nsd)
+		}
+	}
+
+	var result = make([]CapacitySplitInfo, 0, len(devices))
+	for _, device := range devices {
+		result = append(result, *splits[device])
+	}
+	return result
+}
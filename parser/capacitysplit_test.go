@@ -0,0 +1,56 @@
+//go:build !integration
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestSplitCapacity(t *testing.T) {
+	results, err := ParseMmDf("fs1", readFixture(t, "mmdf_split.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	splits := SplitCapacity(results)
+	if len(splits) != 1 {
+		t.Fatalf("expected a single split for fs1, got %d", len(splits))
+	}
+
+	expectCategory := func(m common.MapStr, category string, nsds int64, size int64, free int64) {
+		t.Helper()
+		c := m[category].(common.MapStr)
+		if c["nsds"] != nsds || c["size"] != size*1024 || c["free"] != free*1024 {
+			t.Errorf("%s: expected %d NSDs of %d KiB with %d KiB free, got %v", category, nsds, size, free, map[string]interface{}(c))
+		}
+	}
+
+	// the NSDs of the data pool are left out
+	split := splits[0].ToMapStr()
+	if split["device"] != "fs1" {
+		t.Errorf("expected device fs1, got %v", split["device"])
+	}
+	expectCategory(split, "metadata_only", 1, 1000, 500)
+	expectCategory(split, "mixed", 1, 2000, 1000)
+	expectCategory(split, "data_only", 1, 3000, 300)
+	expectCategory(split, "metadata", 2, 3000, 1500)
+	expectCategory(split, "data", 2, 5000, 1300)
+	if p := split["metadata"].(common.MapStr)["free_percentage"]; p != 50.0 {
+		t.Errorf("expected 50%% free metadata capacity, got %v", p)
+	}
+
+	results, err = ParseMmDf("fs2", readFixture(t, "mmdf_split.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dataOnly []ParseResult
+	for _, r := range results {
+		if nsd, ok := r.(*MmDfNSDInfo); ok && nsd.storagePool == "data" {
+			dataOnly = append(dataOnly, r)
+		}
+	}
+	if splits := SplitCapacity(dataOnly); len(splits) != 0 {
+		t.Errorf("expected no split without system pool NSDs, got %d", len(splits))
+	}
+}
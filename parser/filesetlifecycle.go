@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"sort"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// Fileset lifecycle actions
+const (
+	FilesetCreated          = "created"
+	FilesetDeleted          = "deleted"
+	FilesetLinked           = "linked"
+	FilesetUnlinked         = "unlinked"
+	FilesetStatusChanged    = "status_changed"
+	FilesetPathChanged      = "path_changed"
+	FilesetMaxInodesChanged = "max_inodes_changed"
+)
+
+// FilesetLifecycleEvent describes a change to a fileset between two mmlsfileset runs
+type FilesetLifecycleEvent struct {
+	action   string
+	fileset  MmLsFilesetInfo
+	oldValue interface{}
+	newValue interface{}
+}
+
+// ToMapStr turns the lifecycle event into a common.MapStr, with the full fileset record attached. For deleted
+// filesets, this is the last record we saw.
+func (e *FilesetLifecycleEvent) ToMapStr() common.MapStr {
+	m := common.MapStr{
+		"action":     e.action,
+		"filesystem": e.fileset.filesystemName,
+		"fileset":    e.fileset.filesetName,
+		"record":     e.fileset.ToMapStr(),
+	}
+	if e.oldValue != nil {
+		m["old"] = e.oldValue
+		m["new"] = e.newValue
+	}
+	return m
+}
+
+// UpdateDevice does not do anything, since we already have that information
+func (e *FilesetLifecycleEvent) UpdateDevice(device string) {}
+
+// Action returns what happened to the fileset
+func (e *FilesetLifecycleEvent) Action() string {
+	return e.action
+}
+
+// statusAction returns the action for a change of the fileset status
+func statusAction(status string) string {
+	switch status {
+	case "Linked":
+		return FilesetLinked
+	case "Unlinked":
+		return FilesetUnlinked
+	}
+	return FilesetStatusChanged
+}
+
+// filesetsPerDevice groups the filesets by filesystem and name
+func filesetsPerDevice(filesets []MmLsFilesetInfo) map[string]map[string]MmLsFilesetInfo {
+	devices := make(map[string]map[string]MmLsFilesetInfo)
+	for _, f := range filesets {
+		if devices[f.filesystemName] == nil {
+			devices[f.filesystemName] = make(map[string]MmLsFilesetInfo)
+		}
+		devices[f.filesystemName][f.filesetName] = f
+	}
+	return devices
+}
+
+// DiffMmLsFileset compares two consecutive mmlsfileset results and returns the lifecycle events. Devices that are
+// missing from either result are skipped, so a device that could not be queried does not show up as all of its
+// filesets being deleted (or created).
+func DiffMmLsFileset(previous []MmLsFilesetInfo, current []MmLsFilesetInfo) []FilesetLifecycleEvent {
+	before := filesetsPerDevice(previous)
+	after := filesetsPerDevice(current)
+
+	var events []FilesetLifecycleEvent
+	for device, filesets := range after {
+		old, ok := before[device]
+		if !ok {
+			continue
+		}
+
+		for name, f := range filesets {
+			o, ok := old[name]
+			if !ok {
+				events = append(events, FilesetLifecycleEvent{action: FilesetCreated, fileset: f})
+				continue
+			}
+			if o.status != f.status {
+				events = append(events, FilesetLifecycleEvent{action: statusAction(f.status), fileset: f, oldValue: o.status, newValue: f.status})
+			}
+			// unlinked filesets have -- as path, the link and unlink events already cover that
+			if o.path != f.path && o.path != "--" && f.path != "--" {
+				events = append(events, FilesetLifecycleEvent{action: FilesetPathChanged, fileset: f, oldValue: o.path, newValue: f.path})
+			}
+			if o.maxInodes != f.maxInodes {
+				events = append(events, FilesetLifecycleEvent{action: FilesetMaxInodesChanged, fileset: f, oldValue: o.maxInodes, newValue: f.maxInodes})
+			}
+		}
+		for name, o := range old {
+			if _, ok := filesets[name]; !ok {
+				events = append(events, FilesetLifecycleEvent{action: FilesetDeleted, fileset: o})
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := &events[i].fileset, &events[j].fileset
+		return a.filesystemName < b.filesystemName || (a.filesystemName == b.filesystemName && a.filesetName < b.filesetName)
+	})
+	return events
+}
@@ -0,0 +1,65 @@
+//go:build !integration
+
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffMmLsFileset(t *testing.T) {
+	output := readFixture(t, "mmlsfileset.txt")
+	previous, err := ParseMmLsFileset("fs1", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		switch {
+		case strings.Contains(line, ":scratch:"):
+			continue // deleted
+		case strings.Contains(line, ":projB:"):
+			line = strings.Replace(line, ":Linked:%2Fgpfs%2Ffs1%2Fprojects%2FprojB:", ":Unlinked:--:", 1)
+		case strings.Contains(line, ":projects:"):
+			line = strings.Replace(line, ":200000:190000:", ":300000:190000:", 1)
+		case strings.Contains(line, ":deep:"):
+			line = strings.Replace(line, "%2Fdeep:", "%2Fdeeper:", 1)
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "mmlsfileset::0:1:::fs1:new:6:524295:Linked:%2Fgpfs%2Ffs1%2Fnew:0:Fri Mar 10 08%3A00%3A00 2023:-:-::chmodAndSetacl:0:0:0:0:0:0:chmodAndSetacl:0:")
+	current, err := ParseMmLsFileset("fs1", strings.Join(lines, "\n")+"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := DiffMmLsFileset(previous, current)
+	expected := []struct {
+		fileset, action string
+		old, new        interface{}
+	}{
+		{"deep", FilesetPathChanged, "/gpfs/fs1/projects/projA/deep", "/gpfs/fs1/projects/projA/deeper"},
+		{"new", FilesetCreated, nil, nil},
+		{"projB", FilesetUnlinked, "Linked", "Unlinked"},
+		{"projects", FilesetMaxInodesChanged, int64(200000), int64(300000)},
+		{"scratch", FilesetDeleted, nil, nil},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+	for i, e := range expected {
+		m := events[i].ToMapStr()
+		if m["fileset"] != e.fileset || events[i].Action() != e.action || m["old"] != e.old || m["new"] != e.new {
+			t.Errorf("expected %+v, got %v", e, map[string]interface{}(m))
+		}
+	}
+	if record := events[4].ToMapStr()["record"]; record == nil {
+		t.Error("expected the last record of the deleted fileset")
+	}
+
+	// a device that could not be queried is not reported as deleted
+	if events := DiffMmLsFileset(previous, nil); len(events) != 0 {
+		t.Errorf("expected no events for a missing device, got %d", len(events))
+	}
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// FilesetUsage joins the fileset information from mmlsfileset with the FILESET quota entry of the same fileset
+type FilesetUsage struct {
+	fileset MmLsFilesetInfo
+	quota   *QuotaInfo
+}
+
+// ToMapStr turns the joined fileset information into a common.MapStr. The quota fields are left out when the
+// fileset has no FILESET quota entry, e.g., when fileset quota are not enabled on the device.
+func (f *FilesetUsage) ToMapStr() common.MapStr {
+	m := common.MapStr{
+		"filesystem":   f.fileset.filesystemName,
+		"fileset":      f.fileset.filesetName,
+		"path":         f.fileset.path,
+		"status":       f.fileset.status,
+		"inode_space":  f.fileset.inodeSpace,
+		"max_inodes":   f.fileset.maxInodes,
+		"alloc_inodes": f.fileset.allocInodes,
+		"free_inodes":  f.fileset.freeInodes,
+		"has_quota":    f.quota != nil,
+	}
+	if f.quota == nil {
+		return m
+	}
+
+	q := f.quota
+	m["block_usage"] = q.blockUsage
+	m["block_soft"] = q.blockSoft
+	m["block_hard"] = q.blockHard
+	m["files_usage"] = q.filesUsage
+	m["files_soft"] = q.filesSoft
+	m["files_hard"] = q.filesHard
+	m["state"] = q.State()
+	if p, ok := q.limitPercentage(); ok {
+		m["percentage"] = p
+	}
+	return m
+}
+
+// UpdateDevice does not do anything, since we already have that information
+func (f *FilesetUsage) UpdateDevice(device string) {}
+
+// JoinFilesetQuota returns a FilesetUsage for every fileset, with the FILESET quota entry for the same filesystem
+// and fileset if there is one
+func JoinFilesetQuota(filesets []MmLsFilesetInfo, quotas []QuotaInfo) []FilesetUsage {
+	type filesetKey struct {
+		filesystem string
+		fileset    string
+	}
+
+	byFileset := make(map[filesetKey]*QuotaInfo)
+	for i := range quotas {
+		q := &quotas[i]
+		if q.kind == "FILESET" {
+			byFileset[filesetKey{q.filesystem, q.fileset}] = q
+		}
+	}
+
+	var result = make([]FilesetUsage, 0, len(filesets))
+	for _, f := range filesets {
+		result = append(result, FilesetUsage{
+			fileset: f,
+			quota:   byFileset[filesetKey{f.filesystemName, f.filesetName}],
+		})
+	}
+	return result
+}
@@ -0,0 +1,41 @@
+//go:build !integration
+
+package parser
+
+import (
+	"testing"
+)
+
+func TestJoinFilesetQuota(t *testing.T) {
+	filesets, err := ParseMmLsFileset("fs1", readFixture(t, "mmlsfileset.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	quotas, err := ParseMmRepQuota(readFixture(t, "mmrepquota_filesets.txt") +
+		"mmrepquota::0:1:::fs1:FILESET:1:projects:8000:10000:20000:0:none:1000:0:0:0:none:i:on:off:::\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usages := JoinFilesetQuota(filesets, quotas)
+	if len(usages) != len(filesets) {
+		t.Fatalf("expected a usage for every fileset, got %d", len(usages))
+	}
+
+	projects := usages[1].ToMapStr()
+	if projects["fileset"] != "projects" || projects["has_quota"] != true || projects["block_usage"] != int64(8000) {
+		t.Errorf("unexpected usage for projects: %v", map[string]interface{}(projects))
+	}
+	if projects["percentage"] != 80.0 || projects["state"] != QuotaStateOK || projects["path"] != "/gpfs/fs1/projects" {
+		t.Errorf("unexpected quota state for projects: %v", map[string]interface{}(projects))
+	}
+
+	// USR entries and FILESET entries of other filesystems are not joined
+	root := usages[0].ToMapStr()
+	if root["has_quota"] != false {
+		t.Errorf("expected no quota for root: %v", map[string]interface{}(root))
+	}
+	if _, ok := root["block_usage"]; ok {
+		t.Error("expected no quota fields without a FILESET entry")
+	}
+}
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"sort"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// InodeSpaceInfo contains the inode usage of an inode space, i.e., of an independent fileset together with the
+// dependent filesets sharing its inodes
+type InodeSpaceInfo struct {
+	filesystem  string
+	inodeSpace  int64
+	owner       string
+	filesets    []string
+	maxInodes   int64
+	allocInodes int64
+	freeInodes  int64
+	nearMax     float64
+}
+
+// UsedInodes returns the number of allocated inodes that are in use
+func (s *InodeSpaceInfo) UsedInodes() int64 {
+	return s.allocInodes - s.freeInodes
+}
+
+// Headroom returns how many inodes GPFS can still allocate automatically before reaching maxInodes
+func (s *InodeSpaceInfo) Headroom() int64 {
+	if s.maxInodes <= s.allocInodes {
+		return 0
+	}
+	return s.maxInodes - s.allocInodes
+}
+
+// AllocNearMax returns true if the allocated inodes are within the configured percentage of maxInodes, i.e., when
+// auto-expansion is about to stop
+func (s *InodeSpaceInfo) AllocNearMax() bool {
+	p, ok := percentage(s.allocInodes, s.maxInodes)
+	return ok && p >= s.nearMax
+}
+
+// ToMapStr turns the inode space information into a common.MapStr
+func (s *InodeSpaceInfo) ToMapStr() common.MapStr {
+	m := common.MapStr{
+		"filesystem":     s.filesystem,
+		"inode_space":    s.inodeSpace,
+		"owner":          s.owner,
+		"filesets":       s.filesets,
+		"max_inodes":     s.maxInodes,
+		"alloc_inodes":   s.allocInodes,
+		"free_inodes":    s.freeInodes,
+		"used_inodes":    s.UsedInodes(),
+		"headroom":       s.Headroom(),
+		"alloc_near_max": s.AllocNearMax(),
+	}
+	if p, ok := percentage(s.UsedInodes(), s.allocInodes); ok {
+		m["used_alloc_percentage"] = p
+	}
+	if p, ok := percentage(s.UsedInodes(), s.maxInodes); ok {
+		m["used_max_percentage"] = p
+	}
+	if p, ok := percentage(s.allocInodes, s.maxInodes); ok {
+		m["alloc_max_percentage"] = p
+	}
+	return m
+}
+
+// UpdateDevice does not do anything, since we already have that information
+func (s *InodeSpaceInfo) UpdateDevice(device string) {}
+
+// InodeSpaces returns the inode usage per inode space. Only the owner of an inode space (the independent fileset)
+// reports the inode counts, the dependent filesets are listed as members. The allocation is flagged once it reaches
+// nearMax percent of maxInodes.
+func InodeSpaces(filesets []MmLsFilesetInfo, nearMax float64) []InodeSpaceInfo {
+	type spaceKey struct {
+		filesystem string
+		inodeSpace int64
+	}
+
+	var keys []spaceKey
+	spaces := make(map[spaceKey]*InodeSpaceInfo)
+	for _, f := range filesets {
+		key := spaceKey{f.filesystemName, f.inodeSpace}
+		s, ok := spaces[key]
+		if !ok {
+			s = &InodeSpaceInfo{filesystem: f.filesystemName, inodeSpace: f.inodeSpace, nearMax: nearMax}
+			spaces[key] = s
+			keys = append(keys, key)
+		}
+		s.filesets = append(s.filesets, f.filesetName)
+		if f.isInodeSpaceOwner {
+			s.owner = f.filesetName
+			s.maxInodes = f.maxInodes
+			s.allocInodes = f.allocInodes
+			s.freeInodes = f.freeInodes
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].filesystem < keys[j].filesystem ||
+			(keys[i].filesystem == keys[j].filesystem && keys[i].inodeSpace < keys[j].inodeSpace)
+	})
+
+	var result = make([]InodeSpaceInfo, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *spaces[key])
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+//go:build !integration
+
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInodeSpaces(t *testing.T) {
+	filesets, err := ParseMmLsFileset("fs1", readFixture(t, "mmlsfileset.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spaces := InodeSpaces(filesets, 90)
+	if len(spaces) != 3 {
+		t.Fatalf("expected 3 inode spaces, got %d", len(spaces))
+	}
+
+	projects := spaces[1]
+	if projects.owner != "projects" || !reflect.DeepEqual(projects.filesets, []string{"projects", "projA", "deep", "scratch"}) {
+		t.Errorf("unexpected owner or members: %s %q", projects.owner, projects.filesets)
+	}
+	if projects.UsedInodes() != 185000 || projects.Headroom() != 10000 || !projects.AllocNearMax() {
+		t.Errorf("unexpected inode usage: used %d, headroom %d, near max %t", projects.UsedInodes(), projects.Headroom(), projects.AllocNearMax())
+	}
+	m := projects.ToMapStr()
+	if m["alloc_max_percentage"] != 95.0 || m["used_max_percentage"] != 92.5 {
+		t.Errorf("unexpected percentages %v and %v", m["alloc_max_percentage"], m["used_max_percentage"])
+	}
+
+	projB := spaces[2]
+	if projB.owner != "projB" || projB.AllocNearMax() || projB.Headroom() != 80000 {
+		t.Errorf("unexpected inode space for projB: %+v", projB)
+	}
+	if InodeSpaces(filesets, 20)[2].AllocNearMax() != true {
+		t.Error("expected the threshold to be inclusive")
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common"
 )
 
-// MmDfNSDInfo represents the `nsd` output line information
+// MmDfNSDInfo represents the `nsd` output line information, mmdf reports sizes and free space in KiB
 type MmDfNSDInfo struct {
 	device                  string
 	version                 int64
@@ -19,6 +19,7 @@ type MmDfNSDInfo struct {
 	freeFragments           int64
 	freeFragmentsPercentage int64
 	diskAvailableForAlloc   string // no idea what this should be
+	blockSizes              MmLsFsAttributes
 }
 
 // ToMapStr turns the nsd information into a common.MapStr
@@ -36,6 +37,7 @@ func (m *MmDfNSDInfo) ToMapStr() common.MapStr {
 		"free_blocks_percentage":    m.freeBlocksPercentage,
 		"free_fragments":            m.freeFragments,
 		"free_fragments_percentage": m.freeFragmentsPercentage,
+		"capacity":                  mmDfCapacityMapStr(m.diskSize, m.freeBlocks, m.freeFragments, m.blockSizes),
 		"info_type":                 "nsd",
 	}
 }
@@ -45,7 +47,7 @@ func (m *MmDfNSDInfo) UpdateDevice(device string) {
 	m.device = device
 }
 
-// MmDfPoolTotalInfo represent the `poolTotal` output line information
+// MmDfPoolTotalInfo represent the `poolTotal` output line information, in KiB
 type MmDfPoolTotalInfo struct {
 	device                  string
 	version                 int64
@@ -56,6 +58,7 @@ type MmDfPoolTotalInfo struct {
 	freeFragments           int64
 	freeFragmentsPercentage int64
 	maxDiskSize             int64
+	blockSizes              MmLsFsAttributes
 }
 
 // ToMapStr turns the pool total information into a common.MapStr
@@ -70,6 +73,7 @@ func (m *MmDfPoolTotalInfo) ToMapStr() common.MapStr {
 		"free_fragments":            m.freeFragments,
 		"free_fragments_percentage": m.freeFragmentsPercentage,
 		"max_disk_size":             m.maxDiskSize,
+		"capacity":                  mmDfCapacityMapStr(m.poolSize, m.freeBlocks, m.freeFragments, m.blockSizes),
 		"info_type":                 "pooltotal",
 	}
 }
@@ -79,7 +83,7 @@ func (m *MmDfPoolTotalInfo) UpdateDevice(device string) {
 	m.device = device
 }
 
-// MmDfFsTotalInfo represents the `fstotal` output line information
+// MmDfFsTotalInfo represents the `fstotal` output line information, in KiB
 type MmDfFsTotalInfo struct {
 	device                  string
 	version                 int64
@@ -88,6 +92,7 @@ type MmDfFsTotalInfo struct {
 	freeBlocksPercentage    int64
 	freeFragments           int64
 	freeFragmentsPercentage int64
+	blockSizes              MmLsFsAttributes
 }
 
 // ToMapStr turns the fs total information into a common.MapStr
@@ -100,6 +105,7 @@ func (m *MmDfFsTotalInfo) ToMapStr() common.MapStr {
 		"free_blocks_percentage":    m.freeBlocksPercentage,
 		"free_fragments":            m.freeFragments,
 		"free_fragments_percentage": m.freeFragmentsPercentage,
+		"capacity":                  mmDfCapacityMapStr(m.fsSize, m.freeBlocks, m.freeFragments, m.blockSizes),
 		"info_type":                 "fstotal",
 	}
 
@@ -138,6 +144,68 @@ func (m *MmDfInodeInfo) UpdateDevice(device string) {
 	m.device = device
 }
 
+// mmDfCapacityMapStr converts the KiB values of mmdf to bytes, so they are comparable across filesystems. The free
+// space in fragments (partially used full blocks) is also given as a ratio of all free space. The block and subblock
+// counts are only added when the block sizes are known.
+func mmDfCapacityMapStr(size int64, freeBlocks int64, freeFragments int64, blockSizes MmLsFsAttributes) common.MapStr {
+	m := common.MapStr{
+		"size":             size * 1024,
+		"free":             (freeBlocks + freeFragments) * 1024,
+		"free_full_blocks": freeBlocks * 1024,
+		"free_fragments":   freeFragments * 1024,
+	}
+	if free := freeBlocks + freeFragments; free > 0 {
+		m["fragmented_free_ratio"] = float64(freeFragments) / float64(free)
+	}
+	if blockSizes.BlockSize > 0 {
+		m["block_size"] = blockSizes.BlockSize
+		m["free_full_block_count"] = freeBlocks * 1024 / blockSizes.BlockSize
+	}
+	if blockSizes.SubblockSize > 0 {
+		m["subblock_size"] = blockSizes.SubblockSize
+		m["free_subblock_count"] = freeFragments * 1024 / blockSizes.SubblockSize
+	}
+	return m
+}
+
+// SetBlockSizes sets the block sizes of the filesystem the NSD belongs to
+func (m *MmDfNSDInfo) SetBlockSizes(blockSizes MmLsFsAttributes) {
+	m.blockSizes = blockSizes
+}
+
+// SetBlockSizes sets the block sizes of the filesystem the pool belongs to
+func (m *MmDfPoolTotalInfo) SetBlockSizes(blockSizes MmLsFsAttributes) {
+	m.blockSizes = blockSizes
+}
+
+// SetBlockSizes sets the block sizes of the filesystem
+func (m *MmDfFsTotalInfo) SetBlockSizes(blockSizes MmLsFsAttributes) {
+	m.blockSizes = blockSizes
+}
+
+// ApplyMmLsFsAttributes sets the block sizes of the device on the mmdf results that report capacity. Metadata-only
+// NSDs and the system pool get the metadata block sizes, the filesystem total those of the data pools.
+func ApplyMmLsFsAttributes(results []ParseResult, attributes map[string]MmLsFsAttributes) {
+	for _, r := range results {
+		switch info := r.(type) {
+		case *MmDfNSDInfo:
+			if info.metadata && !info.data {
+				info.SetBlockSizes(attributes[info.device].Metadata())
+			} else {
+				info.SetBlockSizes(attributes[info.device])
+			}
+		case *MmDfPoolTotalInfo:
+			if info.poolName == "system" {
+				info.SetBlockSizes(attributes[info.device].Metadata())
+			} else {
+				info.SetBlockSizes(attributes[info.device])
+			}
+		case *MmDfFsTotalInfo:
+			info.SetBlockSizes(attributes[info.device])
+		}
+	}
+}
+
 func parseMmDfCallback(fields []string, fieldMap map[string]int) ParseResult {
 
 	var identifierFieldLocation = 1
@@ -0,0 +1,110 @@
+//go:build !integration
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestParseMmLsFsAttributes(t *testing.T) {
+	attributes, err := ParseMmLsFsAttributes(readFixture(t, "mmlsfs_attributes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fs1 has a separate metadata block size, fs2 does not
+	expected := map[string]MmLsFsAttributes{
+		"fs1": {BlockSize: 4194304, SubblockSize: 131072, MetadataBlockSize: 262144, MetadataSubblockSize: 8192},
+		"fs2": {BlockSize: 1048576, SubblockSize: 32768},
+	}
+	if len(attributes) != len(expected) {
+		t.Fatalf("expected %d devices, got %v", len(expected), attributes)
+	}
+	for device, e := range expected {
+		if attributes[device] != e {
+			t.Errorf("%s: expected %+v, got %+v", device, e, attributes[device])
+		}
+	}
+	if m := attributes["fs2"].Metadata(); m != (MmLsFsAttributes{BlockSize: 1048576, SubblockSize: 32768}) {
+		t.Errorf("fs2: expected the data block sizes for the metadata, got %+v", m)
+	}
+}
+
+// capacities returns the capacity of the mmdf results that report one, by NSD name, pool name or "fsTotal"
+func capacities(results []ParseResult) map[string]common.MapStr {
+	c := make(map[string]common.MapStr)
+	for _, r := range results {
+		switch info := r.(type) {
+		case *MmDfNSDInfo:
+			c[info.nsdname] = info.ToMapStr()["capacity"].(common.MapStr)
+		case *MmDfPoolTotalInfo:
+			c[info.poolName] = info.ToMapStr()["capacity"].(common.MapStr)
+		case *MmDfFsTotalInfo:
+			c["fsTotal"] = info.ToMapStr()["capacity"].(common.MapStr)
+		}
+	}
+	return c
+}
+
+func TestMmDfCapacity(t *testing.T) {
+	results, err := ParseMmDf("fs1", readFixture(t, "mmdf.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 10 {
+		t.Fatalf("expected 6 NSDs, 2 pools, the filesystem total and the inodes, got %d results", len(results))
+	}
+
+	// without the block sizes there are no block counts
+	c := capacities(results)
+	if len(c) != 9 {
+		t.Fatalf("expected 9 results with a capacity, got %d", len(c))
+	}
+	d1 := c["d1"]
+	if d1["size"] != int64(10485760*1024) || d1["free"] != int64((5242880+10240)*1024) ||
+		d1["free_full_blocks"] != int64(5242880*1024) || d1["free_fragments"] != int64(10240*1024) ||
+		d1["fragmented_free_ratio"] != 10240.0/(5242880+10240) {
+		t.Errorf("unexpected capacity of d1 %v", map[string]interface{}(d1))
+	}
+	if _, ok := d1["block_size"]; ok {
+		t.Error("expected no block counts without the block sizes")
+	}
+
+	attributes, err := ParseMmLsFsAttributes(readFixture(t, "mmlsfs_attributes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyMmLsFsAttributes(results, attributes)
+	c = capacities(results)
+	d1 = c["d1"]
+	if d1["block_size"] != int64(4194304) || d1["free_full_block_count"] != int64(1280) ||
+		d1["subblock_size"] != int64(131072) || d1["free_subblock_count"] != int64(80) {
+		t.Errorf("unexpected block counts of d1 %v", map[string]interface{}(d1))
+	}
+	if c["d2"]["fragmented_free_ratio"] != 0.0 || c["d2"]["free_subblock_count"] != int64(0) {
+		t.Errorf("unexpected capacity of d2 %v", map[string]interface{}(c["d2"]))
+	}
+	if c["data"]["size"] != int64(41943040*1024) || c["data"]["free_full_block_count"] != int64(16777216/4096) {
+		t.Errorf("unexpected capacity of the data pool %v", map[string]interface{}(c["data"]))
+	}
+	// the metadata-only NSDs and the system pool use the metadata block sizes
+	if sys1 := c["sys1"]; sys1["block_size"] != int64(262144) || sys1["free_full_block_count"] != int64(524288/256) ||
+		sys1["subblock_size"] != int64(8192) || sys1["free_subblock_count"] != int64(1024/8) {
+		t.Errorf("unexpected block counts of sys1 %v", map[string]interface{}(sys1))
+	}
+	if system := c["system"]; system["block_size"] != int64(262144) || system["free_full_block_count"] != int64(917504/256) ||
+		system["subblock_size"] != int64(8192) {
+		t.Errorf("unexpected capacity of the system pool %v", map[string]interface{}(system))
+	}
+	if total := c["fsTotal"]; total["free"] != int64((17694720+13312)*1024) || total["block_size"] != int64(4194304) {
+		t.Errorf("unexpected capacity of the filesystem %v", map[string]interface{}(total))
+	}
+
+	// the block sizes of another device do not apply
+	results, _ = ParseMmDf("fs3", readFixture(t, "mmdf.txt"))
+	ApplyMmLsFsAttributes(results, attributes)
+	if _, ok := capacities(results)["d1"]["block_size"]; ok {
+		t.Error("expected no block counts for a device without block sizes")
+	}
+}
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// MmFsLogEntry contains a single line from mmfs.log, with the structured information we could extract from it
+type MmFsLogEntry struct {
+	timestamp time.Time
+	severity  string
+	message   string
+	eventType string
+	details   common.MapStr
+}
+
+// ToMapStr turns the log entry into a common.MapStr
+func (m *MmFsLogEntry) ToMapStr() common.MapStr {
+	ms := common.MapStr{
+		"severity":   m.severity,
+		"message":    m.message,
+		"event_type": m.eventType,
+	}
+	if len(m.details) > 0 {
+		ms[m.eventType] = m.details
+	}
+	return ms
+}
+
+// UpdateDevice does not do anything, log lines are not tied to a single device
+func (m *MmFsLogEntry) UpdateDevice(device string) {}
+
+// Timestamp returns the time at which GPFS logged the line
+func (m *MmFsLogEntry) Timestamp() time.Time {
+	return m.timestamp
+}
+
+// mmFsLogSeverities maps the single letter severity tags to a readable name
+var mmFsLogSeverities = map[string]string{
+	"D": "debug",
+	"I": "info",
+	"N": "notice",
+	"W": "warning",
+	"E": "error",
+	"X": "critical",
+}
+
+// newer GPFS versions use an ISO like timestamp, older versions a ctime like timestamp
+var mmFsLogISOLine = regexp.MustCompile(`^(\d{4}-\d\d-\d\d_\d\d:\d\d:\d\d(?:\.\d+)?[+-]\d{4}): (?:\[(\w)\] )?(.*)$`)
+var mmFsLogCtimeLine = regexp.MustCompile(`^(\w{3} \w{3} [ \d]\d \d\d:\d\d:\d\d(?:\.\d+)? \d{4}): (?:\[(\w)\] )?(.*)$`)
+
+// mmFsLogPattern turns a message matching the regular expression into a typed event, the names of the
+// capture groups become the fields of the event
+type mmFsLogPattern struct {
+	eventType string
+	re        *regexp.Regexp
+}
+
+var mmFsLogPatterns = []mmFsLogPattern{
+	{"expel", regexp.MustCompile(`(?i)expel(?:ling|led)?:? (?:node )?(?P<node_ip>[\d.:a-f]+) \((?P<node>[^)]+)\)`)},
+	{"disk_down", regexp.MustCompile(`(?i)disk failure\.\s+volume (?P<disk>\S+?)\.?\s.*?rc = (?P<rc>\d+)`)},
+	{"disk_down", regexp.MustCompile(`(?i)disk (?P<disk>\S+) (?:is|has been|changed to|marked) (?:down|stopped|unavailable)`)},
+	{"unmount", regexp.MustCompile(`(?i)(?:unmounting file system|file system (?:is being )?unmounted:?)\s*(?P<filesystem>\S+?)\.?(?:\s|$)`)},
+	{"unmount", regexp.MustCompile(`(?i)file system (?P<filesystem>\S+) (?:has been |was )?unmounted`)},
+	{"quorum_loss", regexp.MustCompile(`(?i)(?:lost quorum|quorum loss|node (?P<node>\S+) lost quorum)`)},
+	{"long_waiter", regexp.MustCompile(`(?i)waiting (?P<seconds>\d+(?:\.\d+)?) sec since \S+,(?: \w+,)? thread (?P<thread_id>\d+) (?P<thread_name>[^:]+): (?P<reason>.*)`)},
+	{"node_join", regexp.MustCompile(`(?i)accepted and connected to (?P<node_ip>[\d.:a-f]+) (?P<node>\S+)`)},
+	{"node_join", regexp.MustCompile(`(?i)node (?P<node_ip>[\d.:a-f]+) \((?P<node>[^)]+)\) (?:has )?joined`)},
+	{"node_leave", regexp.MustCompile(`(?i)close connection to (?P<node_ip>[\d.:a-f]+) (?P<node>\S+)`)},
+	{"node_leave", regexp.MustCompile(`(?i)node (?P<node_ip>[\d.:a-f]+) \((?P<node>[^)]+)\) (?:has )?left`)},
+}
+
+// mmFsLogNumericFields are the capture groups that should be published as numbers
+var mmFsLogNumericFields = map[string]bool{
+	"rc":        true,
+	"seconds":   true,
+	"thread_id": true,
+}
+
+// parseMmFsLogTimestamp handles both timestamp formats found in mmfs.log
+func parseMmFsLogTimestamp(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02_15:04:05-0700", s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("Mon Jan _2 15:04:05 2006", s, time.Local)
+}
+
+// classifyMmFsLogMessage finds the first known pattern matching the message
+func classifyMmFsLogMessage(message string) (string, common.MapStr) {
+	for _, p := range mmFsLogPatterns {
+		match := p.re.FindStringSubmatch(message)
+		if match == nil {
+			continue
+		}
+		details := common.MapStr{}
+		for i, name := range p.re.SubexpNames() {
+			if name == "" || match[i] == "" {
+				continue
+			}
+			if mmFsLogNumericFields[name] {
+				v, err := strconv.ParseFloat(match[i], 64)
+				if err == nil {
+					details[name] = v
+					continue
+				}
+			}
+			details[name] = match[i]
+		}
+		return p.eventType, details
+	}
+	return "message", nil
+}
+
+// ParseMmFsLogLine converts a single mmfs.log line into a log entry. Lines that do not start with a
+// timestamp (e.g., continuation lines of a dump) are not parsed and false is returned.
+func ParseMmFsLogLine(line string) (*MmFsLogEntry, bool) {
+	match := mmFsLogISOLine.FindStringSubmatch(line)
+	if match == nil {
+		match = mmFsLogCtimeLine.FindStringSubmatch(line)
+	}
+	if match == nil {
+		return nil, false
+	}
+
+	timestamp, err := parseMmFsLogTimestamp(match[1])
+	if err != nil {
+		return nil, false
+	}
+
+	severity, ok := mmFsLogSeverities[match[2]]
+	if !ok {
+		severity = "info"
+	}
+
+	eventType, details := classifyMmFsLogMessage(match[3])
+
+	return &MmFsLogEntry{
+		timestamp: timestamp,
+		severity:  severity,
+		message:   match[3],
+		eventType: eventType,
+		details:   details,
+	}, true
+}
@@ -0,0 +1,68 @@
+//go:build !integration
+
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMmFsLogLine(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(readFixture(t, "mmfs.log")), "\n")
+
+	cases := []struct {
+		severity  string
+		eventType string
+		details   map[string]interface{}
+	}{
+		{"info", "node_join", map[string]interface{}{"node_ip": "10.1.2.3", "node": "node123"}},
+		{"error", "expel", map[string]interface{}{"node_ip": "10.1.2.4", "node": "node124"}},
+		{"warning", "disk_down", map[string]interface{}{"disk": "nsd05"}},
+		{"notice", "unmount", map[string]interface{}{"filesystem": "fs1"}},
+		{"critical", "quorum_loss", nil},
+		{"info", "long_waiter", map[string]interface{}{"seconds": 120.5, "thread_id": float64(12345), "thread_name": "SharedHashTabFetchHandlerThread"}},
+		{"info", "node_leave", map[string]interface{}{"node_ip": "10.1.2.6", "node": "node126"}},
+		{"info", "message", nil},
+	}
+	if len(lines) != len(cases)+1 {
+		t.Fatalf("fixture has %d lines, expected %d", len(lines), len(cases)+1)
+	}
+
+	for i, c := range cases {
+		entry, ok := ParseMmFsLogLine(lines[i])
+		if !ok {
+			t.Errorf("line %d was not parsed: %s", i, lines[i])
+			continue
+		}
+		if entry.severity != c.severity || entry.eventType != c.eventType {
+			t.Errorf("line %d: expected %s/%s, got %s/%s", i, c.severity, c.eventType, entry.severity, entry.eventType)
+		}
+		for k, v := range c.details {
+			if entry.details[k] != v {
+				t.Errorf("line %d: expected %s=%v, got %v", i, k, v, entry.details[k])
+			}
+		}
+	}
+
+	if _, ok := ParseMmFsLogLine(lines[len(lines)-1]); ok {
+		t.Errorf("continuation lines should not be parsed")
+	}
+}
+
+func TestParseMmFsLogTimestamps(t *testing.T) {
+	iso, _ := ParseMmFsLogLine("2023-03-09_10:15:02.123+0100: [I] something")
+	expected := time.Date(2023, 3, 9, 9, 15, 2, 123000000, time.UTC)
+	if !iso.Timestamp().Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, iso.Timestamp())
+	}
+
+	ctime, ok := ParseMmFsLogLine("Thu Mar  9 10:19:30.123 2023: something without severity")
+	if !ok {
+		t.Fatalf("ctime line was not parsed")
+	}
+	expected = time.Date(2023, 3, 9, 10, 19, 30, 123000000, time.Local)
+	if !ctime.Timestamp().Equal(expected) || ctime.severity != "info" {
+		t.Errorf("expected %s with severity info, got %s with severity %s", expected, ctime.Timestamp(), ctime.severity)
+	}
+}
@@ -2,12 +2,15 @@ package parser
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
 )
 
-// MmLsFilesetInfo contains relevant information about GPFS filesets. For now, we ignore AFM info, as we do no use it.
+// MmLsFilesetInfo contains relevant information about GPFS filesets. The AFM information is only filled in
+// when mmlsfileset was called with --afm.
 type MmLsFilesetInfo struct {
 	device            string
 	version           int64
@@ -29,6 +32,83 @@ type MmLsFilesetInfo struct {
 	snapID            int64
 	permChangeFlag    string
 	freeInodes        int64
+	afm               MmLsFilesetAFMInfo
+	tree              MmLsFilesetTreeInfo
+}
+
+// MmLsFilesetTreeInfo contains the position of a fileset in the junction tree of its device
+type MmLsFilesetTreeInfo struct {
+	parentName      string
+	depth           int64
+	inodeSpaceOwner string
+	ancestors       []string
+}
+
+// ToMapStr returns the tree information in a common.MapStr
+func (t *MmLsFilesetTreeInfo) ToMapStr() common.MapStr {
+	ancestors := t.ancestors
+	if ancestors == nil {
+		ancestors = []string{}
+	}
+	return common.MapStr{
+		"parent_name":       t.parentName,
+		"depth":             t.depth,
+		"inode_space_owner": t.inodeSpaceOwner,
+		"ancestors":         ancestors,
+	}
+}
+
+// MmLsFilesetAFMInfo contains the AFM attributes of a fileset
+type MmLsFilesetAFMInfo struct {
+	target          string
+	mode            string
+	asyncDelay      int64
+	prefetchThreads int64
+	gateway         string
+}
+
+// afmModes maps the AFM modes reported by mmlsfileset to their usual abbreviation
+var afmModes = map[string]string{
+	"ro":                 "RO",
+	"read-only":          "RO",
+	"sw":                 "SW",
+	"single-writer":      "SW",
+	"iw":                 "IW",
+	"independent-writer": "IW",
+	"lu":                 "LU",
+	"local-updates":      "LU",
+}
+
+// ToMapStr returns the AFM information in a common.MapStr
+func (a *MmLsFilesetAFMInfo) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"enabled":          a.target != "",
+		"target":           a.target,
+		"mode":             a.mode,
+		"async_delay":      a.asyncDelay,
+		"prefetch_threads": a.prefetchThreads,
+		"gateway":          a.gateway,
+	}
+}
+
+// IsAFM returns true if the fileset is an AFM cache fileset
+func (m *MmLsFilesetInfo) IsAFM() bool {
+	return m.afm.target != ""
+}
+
+// Filesystem returns the name of the filesystem the fileset belongs to
+func (m *MmLsFilesetInfo) Filesystem() string {
+	return m.filesystemName
+}
+
+// Name returns the name of the fileset
+func (m *MmLsFilesetInfo) Name() string {
+	return m.filesetName
+}
+
+// Path returns the junction path of the fileset, -- when it is unlinked
+func (m *MmLsFilesetInfo) Path() string {
+	return m.path
 }
 
 // ToMapStr returns the fileset information in a common.MapStr
@@ -53,6 +133,8 @@ func (m *MmLsFilesetInfo) ToMapStr() common.MapStr {
 		"snap_ID":             m.snapID,
 		"perm_change_flag":    m.permChangeFlag,
 		"free_inodes":         m.freeInodes,
+		"afm":                 m.afm.ToMapStr(),
+		"tree":                m.tree.ToMapStr(),
 	}
 
 }
@@ -69,10 +151,11 @@ func parseMmLsFilesetCallback(fields []string, fieldMap map[string]int) ParseRes
 		panic(err)
 	}
 
+	// the root fileset and unlinked filesets have no parent, shown as - or -- depending on the GPFS release
 	var parentID int64
 	parentID = -1
-	if fields[fieldMap["parentId"]] == "-" {
-		parentID = parseCertainInt(fields[fieldMap["parentId"]])
+	if parent := fields[fieldMap["parentId"]]; parent != "-" && parent != "--" && parent != "" {
+		parentID = parseCertainInt(parent)
 	}
 
 	return &MmLsFilesetInfo{
@@ -95,6 +178,114 @@ func parseMmLsFilesetCallback(fields []string, fieldMap map[string]int) ParseRes
 		snapID:            parseCertainInt(fields[fieldMap["snapId"]]),
 		permChangeFlag:    fields[fieldMap["permChangeFlag"]],
 		freeInodes:        parseCertainInt(fields[fieldMap["freeInodes"]]),
+		afm:               parseMmLsFilesetAFM(fields, fieldMap),
+	}
+}
+
+// afmColumns lists the columns we read from the --afm output. Some columns were renamed between GPFS releases, so
+// each entry holds the names to try in order.
+var afmColumns = [][]string{
+	{"afmTarget"},
+	{"afmMode"},
+	{"afmAsyncDelay"},
+	{"afmPrefetchThreads", "afmNumPrefetchThreads"},
+	{"afmGateway", "afmGatewayNode"},
+}
+
+// afmColumnsWarning makes sure we only complain once about missing AFM columns
+var afmColumnsWarning sync.Once
+
+// missingAFMColumns returns the AFM columns that are not in the header. Without --afm, none of the AFM columns are
+// there and nothing is reported missing.
+func missingAFMColumns(fieldMap map[string]int) []string {
+	if _, ok := fieldMap["afmTarget"]; !ok {
+		return nil
+	}
+	var missing []string
+	for _, names := range afmColumns {
+		if afmColumn(fieldMap, names) == "" {
+			missing = append(missing, strings.Join(names, "/"))
+		}
+	}
+	return missing
+}
+
+// afmColumn returns the first of the names present in the header
+func afmColumn(fieldMap map[string]int, names []string) string {
+	for _, name := range names {
+		if _, ok := fieldMap[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// parseMmLsFilesetAFM extracts the AFM columns, which are only present when running with --afm
+func parseMmLsFilesetAFM(fields []string, fieldMap map[string]int) MmLsFilesetAFMInfo {
+	if missing := missingAFMColumns(fieldMap); len(missing) > 0 {
+		afmColumnsWarning.Do(func() {
+			logp.Warn("mmlsfileset --afm output lacks the columns %q, these AFM attributes will be empty", missing)
+		})
+	}
+
+	target := decodeGpfsString(optionalField(fields, fieldMap, "afmTarget"))
+	if target == "-" {
+		target = ""
+	}
+	mode := optionalField(fields, fieldMap, "afmMode")
+	if m, ok := afmModes[strings.ToLower(mode)]; ok {
+		mode = m
+	} else if mode == "-" {
+		mode = ""
+	}
+	gateway := optionalField(fields, fieldMap, afmColumn(fieldMap, afmColumns[4]))
+	if gateway == "-" {
+		gateway = ""
+	}
+
+	return MmLsFilesetAFMInfo{
+		target:          target,
+		mode:            mode,
+		asyncDelay:      parseOptionalInt(optionalField(fields, fieldMap, "afmAsyncDelay")),
+		prefetchThreads: parseOptionalInt(optionalField(fields, fieldMap, afmColumn(fieldMap, afmColumns[3]))),
+		gateway:         gateway,
+	}
+}
+
+// buildFilesetTree fills in the parent, ancestors and inode space owner of the filesets of a single device. The
+// ancestors are ordered from the root fileset down to the parent.
+func buildFilesetTree(filesets []MmLsFilesetInfo) {
+	byID := make(map[int64]*MmLsFilesetInfo, len(filesets))
+	owners := make(map[int64]string)
+	for i := range filesets {
+		f := &filesets[i]
+		byID[f.ID] = f
+		if f.isInodeSpaceOwner {
+			owners[f.inodeSpace] = f.filesetName
+		}
+	}
+
+	for i := range filesets {
+		f := &filesets[i]
+		f.tree.inodeSpaceOwner = owners[f.inodeSpace]
+
+		var ancestors []string
+		seen := map[int64]bool{f.ID: true}
+		for parentID := f.parentID; parentID >= 0 && !seen[parentID]; {
+			parent, ok := byID[parentID]
+			if !ok {
+				break
+			}
+			seen[parentID] = true
+			ancestors = append([]string{parent.filesetName}, ancestors...)
+			parentID = parent.parentID
+		}
+
+		if len(ancestors) > 0 {
+			f.tree.parentName = ancestors[len(ancestors)-1]
+		}
+		f.tree.ancestors = ancestors
+		f.tree.depth = int64(len(ancestors))
 	}
 }
 
@@ -110,6 +301,7 @@ func ParseMmLsFileset(device string, output string) ([]MmLsFilesetInfo, error) {
 	for _, f := range fs {
 		filesetInfos = append(filesetInfos, *(f.(*MmLsFilesetInfo)))
 	}
+	buildFilesetTree(filesetInfos)
 
 	return filesetInfos, nil
 }
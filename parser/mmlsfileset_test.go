@@ -0,0 +1,103 @@
+//go:build !integration
+
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestParseMmLsFilesetAFM(t *testing.T) {
+	filesets, err := ParseMmLsFileset("fs1", readFixture(t, "mmlsfileset_afm.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filesets) != 3 {
+		t.Fatalf("expected 3 filesets, got %d", len(filesets))
+	}
+
+	root, cache := filesets[0], filesets[1]
+	if root.IsAFM() {
+		t.Errorf("root should not be an AFM fileset")
+	}
+	if !cache.IsAFM() {
+		t.Fatalf("cache should be an AFM fileset")
+	}
+
+	expected := MmLsFilesetAFMInfo{
+		target:          "nfs://home01/gpfs/home/cache",
+		mode:            "IW",
+		asyncDelay:      15,
+		prefetchThreads: 4,
+		gateway:         "gw01",
+	}
+	if cache.afm != expected {
+		t.Errorf("expected %+v, got %+v", expected, cache.afm)
+	}
+	if root.afm != (MmLsFilesetAFMInfo{}) {
+		t.Errorf("expected no AFM attributes for root, got %+v", root.afm)
+	}
+}
+
+func TestMissingAFMColumns(t *testing.T) {
+	header := func(names ...string) map[string]int {
+		m := make(map[string]int)
+		for i, name := range names {
+			m[name] = i
+		}
+		return m
+	}
+
+	cases := []struct {
+		name     string
+		fieldMap map[string]int
+		missing  []string
+	}{
+		{"without --afm", header("filesetName", "maxInodes"), nil},
+		{"current names", header("afmTarget", "afmMode", "afmAsyncDelay", "afmPrefetchThreads", "afmGateway"), nil},
+		{"alternative names", header("afmTarget", "afmMode", "afmAsyncDelay", "afmNumPrefetchThreads", "afmGatewayNode"), nil},
+		{"missing gateway", header("afmTarget", "afmMode", "afmAsyncDelay", "afmPrefetchThreads"), []string{"afmGateway/afmGatewayNode"}},
+	}
+	for _, c := range cases {
+		if missing := missingAFMColumns(c.fieldMap); !reflect.DeepEqual(missing, c.missing) {
+			t.Errorf("%s: expected %q missing, got %q", c.name, c.missing, missing)
+		}
+	}
+}
+
+func TestBuildFilesetTree(t *testing.T) {
+	filesets, err := ParseMmLsFileset("fs1", readFixture(t, "mmlsfileset.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filesets) != 6 {
+		t.Fatalf("expected 6 filesets, got %d", len(filesets))
+	}
+
+	expected := map[string]MmLsFilesetTreeInfo{
+		"root":     {inodeSpaceOwner: "root"},
+		"projects": {parentName: "root", depth: 1, inodeSpaceOwner: "projects", ancestors: []string{"root"}},
+		"projA":    {parentName: "projects", depth: 2, inodeSpaceOwner: "projects", ancestors: []string{"root", "projects"}},
+		"projB":    {parentName: "projects", depth: 2, inodeSpaceOwner: "projB", ancestors: []string{"root", "projects"}},
+		"deep":     {parentName: "projA", depth: 3, inodeSpaceOwner: "projects", ancestors: []string{"root", "projects", "projA"}},
+		"scratch":  {inodeSpaceOwner: "projects"},
+	}
+	for _, f := range filesets {
+		if !reflect.DeepEqual(f.tree, expected[f.filesetName]) {
+			t.Errorf("%s: expected %+v, got %+v", f.filesetName, expected[f.filesetName], f.tree)
+		}
+	}
+
+	root := filesets[0]
+	if root.parentID != -1 || filesets[5].parentID != -1 {
+		t.Errorf("expected no parent for root and the unlinked fileset, got %d and %d", root.parentID, filesets[5].parentID)
+	}
+	if root.Path() != "/gpfs/fs1" || filesets[5].Path() != "--" {
+		t.Errorf("unexpected paths %s and %s", root.Path(), filesets[5].Path())
+	}
+	if ancestors := root.ToMapStr()["tree"].(common.MapStr)["ancestors"].([]string); ancestors == nil || len(ancestors) != 0 {
+		t.Errorf("expected an empty list of ancestors for root, got %v", ancestors)
+	}
+}
@@ -1,6 +1,10 @@
 package parser
 
-import "github.com/elastic/beats/v7/libbeat/common"
+import (
+	"strconv"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
 
 // MmLsFsInfo contains the relevant information from a single mmlsfs run
 type MmLsFsInfo struct {
@@ -44,3 +48,82 @@ func ParseMmLsFs(output string) ([]string, error) {
 func parseMmLsFsCallback(fields []string, fieldMap map[string]int) ParseResult {
 	return &MmLsFsInfo{deviceName: fields[fieldMap["deviceName"]]}
 }
+
+// MmLsFsAttributes contains the block sizes of a filesystem, in bytes. The metadata sizes are only set when the
+// filesystem has a separate metadata block size for the system pool.
+type MmLsFsAttributes struct {
+	BlockSize            int64
+	SubblockSize         int64
+	MetadataBlockSize    int64
+	MetadataSubblockSize int64
+}
+
+// Metadata returns the block sizes of the system pool, which are those of the data pools unless the filesystem has
+// a separate metadata block size
+func (a MmLsFsAttributes) Metadata() MmLsFsAttributes {
+	m := MmLsFsAttributes{BlockSize: a.BlockSize, SubblockSize: a.SubblockSize}
+	if a.MetadataBlockSize > 0 {
+		m.BlockSize = a.MetadataBlockSize
+	}
+	if a.MetadataSubblockSize > 0 {
+		m.SubblockSize = a.MetadataSubblockSize
+	}
+	return m
+}
+
+// mmLsFsAttribute represents a single attribute line of mmlsfs
+type mmLsFsAttribute struct {
+	deviceName string
+	fieldName  string
+	data       string
+}
+
+func (m *mmLsFsAttribute) ToMapStr() common.MapStr { return nil }
+func (m *mmLsFsAttribute) UpdateDevice(string)     {}
+
+// parseMmLsFsAttributeCallback returns the attribute found in the fields
+func parseMmLsFsAttributeCallback(fields []string, fieldMap map[string]int) ParseResult {
+	return &mmLsFsAttribute{
+		deviceName: optionalField(fields, fieldMap, "deviceName"),
+		fieldName:  optionalField(fields, fieldMap, "fieldName"),
+		data:       decodeGpfsString(optionalField(fields, fieldMap, "data")),
+	}
+}
+
+// ParseMmLsFsAttributes returns the block size and subblock (minimum fragment) size per device from the output of
+// `mmlsfs <device> -B -f -Y`
+func ParseMmLsFsAttributes(output string) (map[string]MmLsFsAttributes, error) {
+	var prefixFieldlocation = 0
+	var identifierFieldLocation = 1
+	var headerFieldLocation = 2
+
+	as, _ := parseGpfsYOutput(prefixFieldlocation, identifierFieldLocation, headerFieldLocation, "mmlsfs", output, parseMmLsFsAttributeCallback)
+
+	attributes := make(map[string]MmLsFsAttributes)
+	for _, a := range as {
+		attr := a.(*mmLsFsAttribute)
+		v, err := strconv.ParseInt(attr.data, 10, 64)
+		if err != nil {
+			continue
+		}
+		// with a separate metadata block size, the sizes of the system pool are listed first and those of the
+		// data pools last
+		fs := attributes[attr.deviceName]
+		switch attr.fieldName {
+		case "blockSize":
+			if fs.BlockSize > 0 {
+				fs.MetadataBlockSize = fs.BlockSize
+			}
+			fs.BlockSize = v
+		case "minFragmentSize":
+			if fs.SubblockSize > 0 {
+				fs.MetadataSubblockSize = fs.SubblockSize
+			}
+			fs.SubblockSize = v
+		default:
+			continue
+		}
+		attributes[attr.deviceName] = fs
+	}
+	return attributes, nil
+}
@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// MmLsQosInfo contains the QoS usage of a single class in a storage pool over the requested interval
+type MmLsQosInfo struct {
+	device      string
+	poolName    string
+	class       string
+	samples     int64
+	iops        float64
+	mbs         float64
+	maxIops     float64
+	maxMBs      float64
+	queueLength float64
+	queueDelay  float64
+	limited     bool
+}
+
+// ToMapStr turns the QoS class information into a common.MapStr
+func (m *MmLsQosInfo) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"device":       m.device,
+		"pool_name":    m.poolName,
+		"class":        m.class,
+		"samples":      m.samples,
+		"iops":         m.iops,
+		"mbs":          m.mbs,
+		"limited":      m.limited,
+		"max_iops":     m.maxIops,
+		"max_mbs":      m.maxMBs,
+		"queue_length": m.queueLength,
+		"queue_delay":  m.queueDelay,
+		"throttled":    m.queueDelay > 0,
+		"info_type":    "qos",
+	}
+}
+
+// UpdateDevice sets the device name
+func (m *MmLsQosInfo) UpdateDevice(device string) {
+	m.device = device
+}
+
+// mmLsQosStats represents a single `stats` output line, i.e., one sample for a class in a pool
+type mmLsQosStats struct {
+	poolName string
+	class    string
+	iops     float64
+	ioql     float64
+	qsdl     float64
+	mbs      float64
+}
+
+func (m *mmLsQosStats) ToMapStr() common.MapStr { return nil }
+func (m *mmLsQosStats) UpdateDevice(string)     {}
+
+// mmLsQosValues represents a single `values` output line, i.e., the configured limits for a pool
+type mmLsQosValues struct {
+	poolName string
+	limits   map[string][2]float64
+}
+
+func (m *mmLsQosValues) ToMapStr() common.MapStr { return nil }
+func (m *mmLsQosValues) UpdateDevice(string)     {}
+
+// parseQosLimit converts a limit such as 300IOPS, 100MB/s or inf into a number, with -1 meaning unlimited
+func parseQosLimit(s string) float64 {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "inf" || s == "-" {
+		return -1
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(s), "iops"), "mb/s")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
+// parseQosFloat parses a numeric stats value, treating anything else as zero
+func parseQosFloat(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// parseQosValues splits an item like other=inf/inf,maintenance/all_local=300IOPS/inf into per class limits
+func parseQosValues(item string) map[string][2]float64 {
+	limits := make(map[string][2]float64)
+	for _, entry := range strings.Split(item, ",") {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		class := strings.SplitN(kv[0], "/", 2)[0]
+		values := strings.SplitN(kv[1], "/", 2)
+		iops := parseQosLimit(values[0])
+		mbs := float64(-1)
+		if len(values) == 2 {
+			mbs = parseQosLimit(values[1])
+		}
+		limits[class] = [2]float64{iops, mbs}
+	}
+	return limits
+}
+
+func parseMmLsQosCallback(fields []string, fieldMap map[string]int) ParseResult {
+
+	var identifierFieldLocation = 1
+
+	switch fields[identifierFieldLocation] {
+	case "stats":
+		return &mmLsQosStats{
+			poolName: optionalField(fields, fieldMap, "pool"),
+			class:    optionalField(fields, fieldMap, "class"),
+			iops:     parseQosFloat(optionalField(fields, fieldMap, "iops")),
+			ioql:     parseQosFloat(optionalField(fields, fieldMap, "ioql")),
+			qsdl:     parseQosFloat(optionalField(fields, fieldMap, "qsdl")),
+			mbs:      parseQosFloat(optionalField(fields, fieldMap, "MBs")),
+		}
+	case "values":
+		return &mmLsQosValues{
+			poolName: optionalField(fields, fieldMap, "pool"),
+			limits:   parseQosValues(decodeGpfsString(optionalField(fields, fieldMap, "item"))),
+		}
+	}
+	return nil
+}
+
+// ParseMmLsQos converts the output of `mmlsqos <device> -Y --seconds N` into averages per pool and class
+func ParseMmLsQos(device string, output string) ([]ParseResult, error) {
+
+	var prefixFieldlocation = 0
+	var identifierFieldLocation = 1
+	var headerFieldLocation = 2
+
+	qs, _ := parseGpfsYOutput(prefixFieldlocation, identifierFieldLocation, headerFieldLocation, "mmlsqos", output, parseMmLsQosCallback)
+
+	limits := make(map[string]map[string][2]float64)
+	infos := make(map[[2]string]*MmLsQosInfo)
+	for _, q := range qs {
+		switch v := q.(type) {
+		case *mmLsQosValues:
+			limits[v.poolName] = v.limits
+		case *mmLsQosStats:
+			key := [2]string{v.poolName, v.class}
+			info, ok := infos[key]
+			if !ok {
+				info = &MmLsQosInfo{device: device, poolName: v.poolName, class: v.class}
+				infos[key] = info
+			}
+			info.samples++
+			info.iops += v.iops
+			info.mbs += v.mbs
+			info.queueLength += v.ioql
+			info.queueDelay += v.qsdl
+		}
+	}
+
+	var keys = make([][2]string, 0, len(infos))
+	for key := range infos {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || (keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+	})
+
+	var qos = make([]ParseResult, 0, len(infos))
+	for _, key := range keys {
+		info := infos[key]
+		n := float64(info.samples)
+		info.iops /= n
+		info.mbs /= n
+		info.queueLength /= n
+		info.queueDelay /= n
+
+		info.maxIops, info.maxMBs = -1, -1
+		if l, ok := limits[info.poolName][info.class]; ok {
+			info.maxIops, info.maxMBs = l[0], l[1]
+		}
+		info.limited = info.maxIops >= 0 || info.maxMBs >= 0
+		qos = append(qos, info)
+	}
+
+	return qos, nil
+}
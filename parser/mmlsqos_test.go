@@ -0,0 +1,70 @@
+//go:build !integration
+
+package parser
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseMmLsQos(t *testing.T) {
+	results, err := ParseMmLsQos("fs1", readFixture(t, "mmlsqos.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 pool/class combinations, got %d", len(results))
+	}
+
+	// sorted on pool and class
+	data := results[0].(*MmLsQosInfo)
+	maintenance := results[1].(*MmLsQosInfo)
+	other := results[2].(*MmLsQosInfo)
+	if data.poolName != "data" || maintenance.class != "maintenance" || other.class != "other" {
+		t.Fatalf("unexpected order: %s/%s, %s/%s, %s/%s", data.poolName, data.class,
+			maintenance.poolName, maintenance.class, other.poolName, other.class)
+	}
+
+	expected := MmLsQosInfo{
+		device:      "fs1",
+		poolName:    "system",
+		class:       "maintenance",
+		samples:     2,
+		iops:        250,
+		mbs:         20,
+		maxIops:     300,
+		maxMBs:      100,
+		queueLength: 2,
+		queueDelay:  0.3,
+		limited:     true,
+	}
+	if math.Abs(maintenance.queueDelay-expected.queueDelay) > 1e-9 {
+		t.Errorf("expected a queue delay of %f, got %f", expected.queueDelay, maintenance.queueDelay)
+	}
+	maintenance.queueDelay = expected.queueDelay
+	if *maintenance != expected {
+		t.Errorf("expected %+v, got %+v", expected, *maintenance)
+	}
+
+	if other.limited || other.maxIops != -1 || other.maxMBs != -1 {
+		t.Errorf("the other class should be unlimited, got %+v", *other)
+	}
+	if m := other.ToMapStr(); m["throttled"] != false {
+		t.Errorf("the other class should not be throttled")
+	}
+}
+
+func TestParseQosLimit(t *testing.T) {
+	cases := map[string]float64{
+		"300IOPS": 300,
+		"100MB/s": 100,
+		"inf":     -1,
+		"":        -1,
+		"bogus":   -1,
+	}
+	for s, expected := range cases {
+		if v := parseQosLimit(s); v != expected {
+			t.Errorf("parseQosLimit(%q): expected %f, got %f", s, expected, v)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// MmLsRecoveryGroupDAInfo contains the information of a single declustered array in a recovery group
+type MmLsRecoveryGroupDAInfo struct {
+	version          int64
+	recoveryGroup    string
+	name             string
+	needsService     bool
+	vdisks           int64
+	pdisks           int64
+	spares           string
+	replaceThreshold int64
+	freeSpace        int64
+	backgroundTask   string
+	taskPriority     string
+	taskProgress     int64
+}
+
+// ToMapStr turns the declustered array information into a common.MapStr
+func (m *MmLsRecoveryGroupDAInfo) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"version":           m.version,
+		"recovery_group":    m.recoveryGroup,
+		"declustered_array": m.name,
+		"needs_service":     m.needsService,
+		"vdisks":            m.vdisks,
+		"pdisks":            m.pdisks,
+		"spares":            m.spares,
+		"replace_threshold": m.replaceThreshold,
+		"free_space":        m.freeSpace,
+		"background_task":   m.backgroundTask,
+		"task_priority":     m.taskPriority,
+		"task_progress":     m.taskProgress,
+		"rebuilding":        m.Rebuilding(),
+		"critical_rebuild":  m.CriticalRebuild(),
+		"info_type":         "declusteredarray",
+	}
+}
+
+// UpdateDevice does not do anything, declustered arrays do not belong to a device
+func (m *MmLsRecoveryGroupDAInfo) UpdateDevice(device string) {}
+
+// Rebuilding returns true if the declustered array is rebuilding data after a pdisk failure
+func (m *MmLsRecoveryGroupDAInfo) Rebuilding() bool {
+	return strings.HasPrefix(m.backgroundTask, "rebuild")
+}
+
+// CriticalRebuild returns true if the declustered array has data without any remaining redundancy
+func (m *MmLsRecoveryGroupDAInfo) CriticalRebuild() bool {
+	return m.backgroundTask == "rebuild-critical"
+}
+
+func parseMmLsRecoveryGroupCallback(fields []string, fieldMap map[string]int) ParseResult {
+
+	var identifierFieldLocation = 1
+
+	switch fields[identifierFieldLocation] {
+	case "declusteredArray":
+		return &MmLsRecoveryGroupDAInfo{
+			version:          parseOptionalInt(optionalField(fields, fieldMap, "version")),
+			recoveryGroup:    optionalField(fields, fieldMap, "recoveryGroupName"),
+			name:             optionalField(fields, fieldMap, "declusteredArrayName"),
+			needsService:     isYes(optionalField(fields, fieldMap, "declusteredArrayNeedsService")),
+			vdisks:           parseOptionalInt(optionalField(fields, fieldMap, "declusteredArrayVdisks")),
+			pdisks:           parseOptionalInt(optionalField(fields, fieldMap, "declusteredArrayPdisks")),
+			spares:           decodeGpfsString(optionalField(fields, fieldMap, "declusteredArraySpares")),
+			replaceThreshold: parseOptionalInt(optionalField(fields, fieldMap, "declusteredArrayReplaceThreshold")),
+			freeSpace:        parseOptionalInt(optionalField(fields, fieldMap, "declusteredArrayFreeSpace")),
+			backgroundTask:   optionalField(fields, fieldMap, "declusteredArrayBackgroundTask"),
+			taskPriority:     optionalField(fields, fieldMap, "declusteredArrayTaskPriority"),
+			taskProgress:     parseOptionalInt(strings.TrimSuffix(optionalField(fields, fieldMap, "declusteredArrayTaskProgress"), "%")),
+		}
+	}
+	return nil
+}
+
+// ParseMmLsRecoveryGroup converts the declustered array lines from `mmlsrecoverygroup <rg> -L -Y` into the desired information
+func ParseMmLsRecoveryGroup(output string) ([]ParseResult, error) {
+
+	var prefixFieldlocation = 0
+	var identifierFieldLocation = 1
+	var headerFieldLocation = 2
+
+	rgs, _ := parseGpfsYOutput(prefixFieldlocation, identifierFieldLocation, headerFieldLocation, "mmlsrecoverygroup", output, parseMmLsRecoveryGroupCallback)
+
+	var das = make([]ParseResult, 0, len(rgs))
+	for _, info := range rgs {
+		if info == nil {
+			continue // we only care about the declustered arrays
+		}
+		das = append(das, info)
+	}
+
+	return das, nil
+}
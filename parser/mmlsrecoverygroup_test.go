@@ -0,0 +1,44 @@
+//go:build !integration
+
+package parser
+
+import (
+	"testing"
+)
+
+func TestParseMmLsRecoveryGroup(t *testing.T) {
+	results, err := ParseMmLsRecoveryGroup(readFixture(t, "mmlsrecoverygroup.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected only the 2 declustered arrays, got %d results", len(results))
+	}
+
+	nvr := results[0].(*MmLsRecoveryGroupDAInfo)
+	if nvr.name != "NVR" || nvr.Rebuilding() || nvr.CriticalRebuild() || nvr.taskProgress != 27 {
+		t.Errorf("unexpected NVR declustered array %+v", *nvr)
+	}
+
+	da := results[1].(*MmLsRecoveryGroupDAInfo)
+	expected := MmLsRecoveryGroupDAInfo{
+		version:          1,
+		recoveryGroup:    "rg2",
+		name:             "DA1",
+		needsService:     true,
+		vdisks:           2,
+		pdisks:           90,
+		spares:           "2,10",
+		replaceThreshold: 2,
+		freeSpace:        71468255805440,
+		backgroundTask:   "rebuild-critical",
+		taskPriority:     "high",
+		taskProgress:     3,
+	}
+	if *da != expected {
+		t.Errorf("expected %+v, got %+v", expected, *da)
+	}
+	if !da.Rebuilding() || !da.CriticalRebuild() {
+		t.Errorf("DA1 should be in a critical rebuild")
+	}
+}
@@ -1,44 +1,389 @@
 package parser
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/elastic/beats/v7/libbeat/common"
 )
 
 // QuotaInfo contains the information of a single entry produced by mmrepquota
 type QuotaInfo struct {
-	filesystem string
-	fileset    string
-	kind       string
-	entity     string
-	blockUsage int64
-	blockSoft  int64
-	blockHard  int64
-	blockDoubt int64
-	blockGrace string
-	filesUsage int64
-	filesSoft  int64
-	filesHard  int64
-	filesDoubt int64
-	filesGrace string
+	filesystem  string
+	fileset     string
+	kind        string
+	entity      string
+	blockUsage  int64
+	blockSoft   int64
+	blockHard   int64
+	blockDoubt  int64
+	blockGrace  string
+	filesUsage  int64
+	filesSoft   int64
+	filesHard   int64
+	filesDoubt  int64
+	filesGrace  string
+	remarks     string // e for explicit limits, d_fset or d_fsys for default limits, i for an initial entry
+	usesDefault bool
+	entityID    string
+	entityName  string
+	collected   time.Time
+}
+
+// Grace states
+const (
+	GraceStateNone    = "none"
+	GraceStateInGrace = "in_grace"
+	GraceStateExpired = "expired"
+	GraceStateUnknown = "unknown"
+)
+
+// graceUnits maps the units mmrepquota uses in the grace column to their duration
+var graceUnits = map[string]time.Duration{
+	"day":     24 * time.Hour,
+	"days":    24 * time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"min":     time.Minute,
+	"mins":    time.Minute,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"sec":     time.Second,
+	"secs":    time.Second,
+}
+
+// QuotaGrace is the parsed content of the grace column
+type QuotaGrace struct {
+	raw       string
+	state     string
+	remaining time.Duration
+}
+
+// ParseQuotaGrace converts the grace column, e.g., none, expired or 6 days, into a QuotaGrace
+func ParseQuotaGrace(s string) QuotaGrace {
+	raw := strings.TrimSpace(decodeGpfsString(s))
+	switch strings.ToLower(raw) {
+	case "none", "":
+		return QuotaGrace{raw: raw, state: GraceStateNone}
+	case "expired":
+		return QuotaGrace{raw: raw, state: GraceStateExpired}
+	}
+
+	parts := strings.Fields(strings.ToLower(raw))
+	if len(parts) == 2 {
+		v, err := strconv.ParseFloat(parts[0], 64)
+		unit, ok := graceUnits[parts[1]]
+		if err == nil && ok {
+			return QuotaGrace{raw: raw, state: GraceStateInGrace, remaining: time.Duration(v * float64(unit))}
+		}
+	}
+	return QuotaGrace{raw: raw, state: GraceStateUnknown}
+}
+
+// State returns the grace state
+func (g QuotaGrace) State() string {
+	return g.state
+}
+
+// Remaining returns the remaining grace period, only meaningful when in grace
+func (g QuotaGrace) Remaining() time.Duration {
+	return g.remaining
+}
+
+// toMapStr returns the grace information, the expiry is estimated from the given collection time
+func (g QuotaGrace) toMapStr(collected time.Time) common.MapStr {
+	m := common.MapStr{
+		"raw":   g.raw,
+		"state": g.state,
+	}
+	if g.state == GraceStateInGrace {
+		m["remaining_seconds"] = int64(g.remaining.Seconds())
+		m["expires"] = collected.Add(g.remaining)
+	}
+	return m
+}
+
+// BlockGrace returns the parsed block grace period
+func (q *QuotaInfo) BlockGrace() QuotaGrace {
+	return ParseQuotaGrace(q.blockGrace)
+}
+
+// FilesGrace returns the parsed files grace period
+func (q *QuotaInfo) FilesGrace() QuotaGrace {
+	return ParseQuotaGrace(q.filesGrace)
+}
+
+// Quota states, ordered from least to most severe
+const (
+	QuotaStateUnlimited    = "unlimited"
+	QuotaStateOK           = "ok"
+	QuotaStateOverSoft     = "over_soft"
+	QuotaStateInGrace      = "in_grace"
+	QuotaStateGraceExpired = "grace_expired"
+	QuotaStateOverHard     = "over_hard"
+)
+
+var quotaStateSeverity = map[string]int{
+	QuotaStateUnlimited:    0,
+	QuotaStateOK:           1,
+	QuotaStateOverSoft:     2,
+	QuotaStateInGrace:      3,
+	QuotaStateGraceExpired: 4,
+	QuotaStateOverHard:     5,
+}
+
+// quotaState determines the state of a single resource (blocks or files), a limit of 0 means there is no limit
+func quotaState(usage int64, soft int64, hard int64, grace QuotaGrace) string {
+	switch {
+	case soft == 0 && hard == 0:
+		return QuotaStateUnlimited
+	case hard > 0 && usage >= hard:
+		return QuotaStateOverHard
+	case soft > 0 && usage > soft:
+		switch grace.State() {
+		case GraceStateExpired:
+			return QuotaStateGraceExpired
+		case GraceStateInGrace:
+			return QuotaStateInGrace
+		default:
+			return QuotaStateOverSoft
+		}
+	}
+	return QuotaStateOK
+}
+
+// worstQuotaState returns the most severe of the given states
+func worstQuotaState(states ...string) string {
+	worst := QuotaStateUnlimited
+	for _, s := range states {
+		if quotaStateSeverity[s] > quotaStateSeverity[worst] {
+			worst = s
+		}
+	}
+	return worst
+}
+
+// percentage returns usage as a percentage of the limit, and false if there is no limit
+func percentage(usage int64, limit int64) (float64, bool) {
+	if limit <= 0 {
+		return 0, false
+	}
+	return 100 * float64(usage) / float64(limit), true
+}
+
+// doubtOverLimit returns true if the usage is within the limit, but adding the in doubt value would exceed it
+func doubtOverLimit(usage int64, doubt int64, limit int64) bool {
+	return limit > 0 && usage <= limit && usage+doubt > limit
+}
+
+// BlockState returns the quota state for the block usage
+func (q *QuotaInfo) BlockState() string {
+	return quotaState(q.blockUsage, q.blockSoft, q.blockHard, q.BlockGrace())
+}
+
+// FilesState returns the quota state for the number of files
+func (q *QuotaInfo) FilesState() string {
+	return quotaState(q.filesUsage, q.filesSoft, q.filesHard, q.FilesGrace())
+}
+
+// State returns the most severe of the block and files quota states
+func (q *QuotaInfo) State() string {
+	return worstQuotaState(q.BlockState(), q.FilesState())
+}
+
+// DoubtOverLimit returns true if the in doubt values could push the entry over one of its limits
+func (q *QuotaInfo) DoubtOverLimit() bool {
+	return doubtOverLimit(q.blockUsage, q.blockDoubt, q.blockSoft) ||
+		doubtOverLimit(q.blockUsage, q.blockDoubt, q.blockHard) ||
+		doubtOverLimit(q.filesUsage, q.filesDoubt, q.filesSoft) ||
+		doubtOverLimit(q.filesUsage, q.filesDoubt, q.filesHard)
 }
 
 // ToMapStr turns the quota information into a common.MapStr
 func (q *QuotaInfo) ToMapStr() common.MapStr {
+	m := q.rawMapStr()
+
+	m["block_state"] = q.BlockState()
+	m["files_state"] = q.FilesState()
+	m["state"] = q.State()
+	m["doubt_over_limit"] = q.DoubtOverLimit()
+	m["block_grace"] = q.BlockGrace().toMapStr(q.collected)
+	m["files_grace"] = q.FilesGrace().toMapStr(q.collected)
+	m["uses_default"] = q.usesDefault
+
+	// percentages are only meaningful when a limit is set
+	if p, ok := percentage(q.blockUsage, q.blockSoft); ok {
+		m["block_soft_percentage"] = p
+	}
+	if p, ok := percentage(q.blockUsage, q.blockHard); ok {
+		m["block_hard_percentage"] = p
+	}
+	if p, ok := percentage(q.filesUsage, q.filesSoft); ok {
+		m["files_soft_percentage"] = p
+	}
+	if p, ok := percentage(q.filesUsage, q.filesHard); ok {
+		m["files_hard_percentage"] = p
+	}
+
+	return m
+}
+
+// QuotaKey identifies a quota entry across mmrepquota runs
+type QuotaKey struct {
+	Filesystem string
+	Fileset    string
+	Kind       string
+	Entity     string
+}
+
+// Key returns the identifier of the quota entry
+func (q *QuotaInfo) Key() QuotaKey {
+	return QuotaKey{Filesystem: q.filesystem, Fileset: q.fileset, Kind: q.kind, Entity: q.entity}
+}
+
+// LimitsDiffer returns true if any of the soft or hard limits differ between the entries
+func (q *QuotaInfo) LimitsDiffer(other *QuotaInfo) bool {
+	return q.blockSoft != other.blockSoft || q.blockHard != other.blockHard ||
+		q.filesSoft != other.filesSoft || q.filesHard != other.filesHard
+}
+
+// UsageDiffers returns true if the block or files usage moved more than the given deltas between the entries
+func (q *QuotaInfo) UsageDiffers(other *QuotaInfo, blockDelta int64, filesDelta int64) bool {
+	return abs(q.blockUsage-other.blockUsage) > blockDelta || abs(q.filesUsage-other.filesUsage) > filesDelta
+}
+
+// QuotaLimits contains the soft and hard limits of a quota entry
+type QuotaLimits struct {
+	BlockSoft int64 `json:"block_soft"`
+	BlockHard int64 `json:"block_hard"`
+	FilesSoft int64 `json:"files_soft"`
+	FilesHard int64 `json:"files_hard"`
+}
+
+// ToMapStr turns the limits into a common.MapStr
+func (l QuotaLimits) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"block_soft": l.BlockSoft,
+		"block_hard": l.BlockHard,
+		"files_soft": l.FilesSoft,
+		"files_hard": l.FilesHard,
+	}
+}
+
+// Limits returns the soft and hard limits of the quota entry
+func (q *QuotaInfo) Limits() QuotaLimits {
+	return QuotaLimits{
+		BlockSoft: q.blockSoft,
+		BlockHard: q.blockHard,
+		FilesSoft: q.filesSoft,
+		FilesHard: q.filesHard,
+	}
+}
+
+// LimitChangeMapStr describes the change from the old limits to the current ones
+func (q *QuotaInfo) LimitChangeMapStr(old QuotaLimits) common.MapStr {
+	current := q.Limits()
+
+	var changed []string
+	for _, c := range []struct {
+		name     string
+		old, new int64
+	}{
+		{"block_soft", old.BlockSoft, current.BlockSoft},
+		{"block_hard", old.BlockHard, current.BlockHard},
+		{"files_soft", old.FilesSoft, current.FilesSoft},
+		{"files_hard", old.FilesHard, current.FilesHard},
+	} {
+		if c.old != c.new {
+			changed = append(changed, c.name)
+		}
+	}
+
+	return common.MapStr{
+		"filesystem": q.filesystem,
+		"fileset":    q.fileset,
+		"kind":       q.kind,
+		"entity":     q.entityMapStr(),
+		"old":        old.ToMapStr(),
+		"new":        current.ToMapStr(),
+		"changed":    changed,
+	}
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Filesystem returns the filesystem of the quota entry
+func (q *QuotaInfo) Filesystem() string {
+	return q.filesystem
+}
+
+// Fileset returns the fileset of the quota entry
+func (q *QuotaInfo) Fileset() string {
+	return q.fileset
+}
+
+// BlockUsage returns the block usage in KiB
+func (q *QuotaInfo) BlockUsage() int64 {
+	return q.blockUsage
+}
+
+// FilesUsage returns the number of files
+func (q *QuotaInfo) FilesUsage() int64 {
+	return q.filesUsage
+}
+
+// Kind returns the quota type, i.e., USR, GRP or FILESET
+func (q *QuotaInfo) Kind() string {
+	return q.kind
+}
+
+// EntityID returns the numeric ID of the user, group or fileset, if known
+func (q *QuotaInfo) EntityID() string {
+	return q.entityID
+}
+
+// EntityName returns the name of the user, group or fileset, if known
+func (q *QuotaInfo) EntityName() string {
+	return q.entityName
+}
+
+// SetEntity updates the ID and name of the entity, e.g., after resolving them
+func (q *QuotaInfo) SetEntity(id string, name string) {
+	q.entityID = id
+	q.entityName = name
+}
+
+// entityMapStr returns both the ID and name of the entity
+func (q *QuotaInfo) entityMapStr() common.MapStr {
+	return common.MapStr{
+		"id":   q.entityID,
+		"name": q.entityName,
+	}
+}
+
+// rawMapStr returns the quota information as reported by mmrepquota, with the entity split into its ID and name
+func (q *QuotaInfo) rawMapStr() common.MapStr {
 	return common.MapStr{
-		"filesystem":    q.filesystem,
-		"fileset":       q.fileset,
-		"kind":          q.kind,
-		"entity":        q.entity,
-		"block_usage":   q.blockUsage,
-		"block_soft":    q.blockSoft,
-		"block_hard":    q.blockHard,
-		"block_doubt":   q.blockDoubt,
-		"block_expired": q.blockGrace,
-		"files_usage":   q.filesUsage,
-		"files_soft":    q.filesSoft,
-		"files_hard":    q.filesHard,
-		"files_doubt":   q.filesDoubt,
-		"files_expired": q.filesGrace,
+		"filesystem":  q.filesystem,
+		"fileset":     q.fileset,
+		"kind":        q.kind,
+		"entity":      q.entityMapStr(),
+		"block_usage": q.blockUsage,
+		"block_soft":  q.blockSoft,
+		"block_hard":  q.blockHard,
+		"block_doubt": q.blockDoubt,
+		"files_usage": q.filesUsage,
+		"files_soft":  q.filesSoft,
+		"files_hard":  q.filesHard,
+		"files_doubt": q.filesDoubt,
 	}
 }
 
@@ -61,6 +406,14 @@ func parseMmRepQuotaCallback(fields []string, fieldMap map[string]int) ParseResu
 		filesHard:  parseCertainInt(fields[fieldMap["filesLimit"]]),
 		filesDoubt: parseCertainInt(fields[fieldMap["filesInDoubt"]]),
 		filesGrace: fields[fieldMap["filesGrace"]],
+		remarks:    optionalField(fields, fieldMap, "remarks"),
+		entityID:   optionalField(fields, fieldMap, "id"),
+	}
+	// mmrepquota prints the ID as the name when it cannot be resolved
+	if _, err := strconv.ParseInt(qi.entity, 10, 64); err == nil && (qi.entityID == "" || qi.entityID == qi.entity) {
+		qi.entityID = qi.entity
+	} else {
+		qi.entityName = qi.entity
 	}
 	if qi.kind == "FILESET" {
 		qi.fileset = qi.entity // filesets have no name, and we need to have a link between FILESET and USR quota
@@ -77,9 +430,12 @@ func ParseMmRepQuota(output string) ([](QuotaInfo), error) {
 
 	qs, _ := parseGpfsYOutput(prefixFieldlocation, identifierFieldLocation, headerFieldLocation, "mmrepquota", output, parseMmRepQuotaCallback)
 
+	collected := time.Now()
 	var quotaInfos = make([](QuotaInfo), 0, len(qs))
 	for _, q := range qs {
-		quotaInfos = append(quotaInfos, *(q.(*QuotaInfo)))
+		qi := q.(*QuotaInfo)
+		qi.collected = collected
+		quotaInfos = append(quotaInfos, *qi)
 	}
 
 	return quotaInfos, nil
@@ -0,0 +1,131 @@
+//go:build !integration
+
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// quotaByEntity returns the parsed quota entries by kind and entity
+func quotaByEntity(t *testing.T, quotas []QuotaInfo) map[[2]string]*QuotaInfo {
+	t.Helper()
+	m := make(map[[2]string]*QuotaInfo, len(quotas))
+	for i := range quotas {
+		m[[2]string{quotas[i].kind, quotas[i].entity}] = &quotas[i]
+	}
+	return m
+}
+
+func TestParseMmRepQuota(t *testing.T) {
+	quotas, err := ParseMmRepQuota(readFixture(t, "mmrepquota.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotas) != 6 {
+		t.Fatalf("expected 6 quota entries, got %d", len(quotas))
+	}
+	byEntity := quotaByEntity(t, quotas)
+
+	alice := byEntity[[2]string{"USR", "alice"}]
+	if alice.EntityID() != "1000" || alice.EntityName() != "alice" || alice.Fileset() != "data" {
+		t.Errorf("unexpected entity for alice: %+v", *alice)
+	}
+	entity := alice.ToMapStr()["entity"].(common.MapStr)
+	if entity["id"] != "1000" || entity["name"] != "alice" {
+		t.Errorf("expected the entity ID and name in the event, got %v", map[string]interface{}(entity))
+	}
+	unresolved := byEntity[[2]string{"USR", "1002"}]
+	if unresolved.EntityID() != "1002" || unresolved.EntityName() != "" {
+		t.Errorf("an unresolved ID should not be taken as a name: %+v", *unresolved)
+	}
+	fileset := byEntity[[2]string{"FILESET", "data"}]
+	if fileset.Fileset() != "data" {
+		t.Errorf("a FILESET entry should be linked to its own fileset, got %s", fileset.Fileset())
+	}
+}
+
+func TestQuotaState(t *testing.T) {
+	quotas, err := ParseMmRepQuota(readFixture(t, "mmrepquota.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byEntity := quotaByEntity(t, quotas)
+
+	cases := []struct {
+		kind, entity           string
+		block, files, state    string
+		doubtOverLimit         bool
+		blockSoftPct, filesPct float64
+	}{
+		{"USR", "alice", QuotaStateInGrace, QuotaStateOK, QuotaStateInGrace, false, 120, 10},
+		{"USR", "bob", QuotaStateOverHard, QuotaStateUnlimited, QuotaStateOverHard, false, 400, 0},
+		{"USR", "1002", QuotaStateOK, QuotaStateUnlimited, QuotaStateOK, true, 20, 0},
+		{"GRP", "users", QuotaStateUnlimited, QuotaStateInGrace, QuotaStateInGrace, false, 0, 150},
+		{"FILESET", "data", QuotaStateUnlimited, QuotaStateUnlimited, QuotaStateUnlimited, false, 0, 0},
+	}
+	for _, c := range cases {
+		q := byEntity[[2]string{c.kind, c.entity}]
+		if q.BlockState() != c.block || q.FilesState() != c.files || q.State() != c.state {
+			t.Errorf("%s %s: expected states %s/%s/%s, got %s/%s/%s", c.kind, c.entity,
+				c.block, c.files, c.state, q.BlockState(), q.FilesState(), q.State())
+		}
+		if q.DoubtOverLimit() != c.doubtOverLimit {
+			t.Errorf("%s %s: expected doubt over limit %t", c.kind, c.entity, c.doubtOverLimit)
+		}
+
+		m := q.ToMapStr()
+		if p, ok := m["block_soft_percentage"]; ok != (c.blockSoftPct > 0) || (ok && p != c.blockSoftPct) {
+			t.Errorf("%s %s: expected block soft percentage %f, got %v", c.kind, c.entity, c.blockSoftPct, p)
+		}
+		if p, ok := m["files_soft_percentage"]; ok != (c.filesPct > 0) || (ok && p != c.filesPct) {
+			t.Errorf("%s %s: expected files soft percentage %f, got %v", c.kind, c.entity, c.filesPct, p)
+		}
+	}
+}
+
+func TestQuotaGrace(t *testing.T) {
+	quotas, err := ParseMmRepQuota(readFixture(t, "mmrepquota.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := quotaByEntity(t, quotas)[[2]string{"USR", "alice"}]
+
+	grace := alice.ToMapStr()["block_grace"].(common.MapStr)
+	if grace["state"] != GraceStateInGrace || grace["remaining_seconds"] != int64(6*24*3600) {
+		t.Errorf("unexpected block grace %v", map[string]interface{}(grace))
+	}
+	if grace["expires"] != alice.collected.Add(6*24*time.Hour) {
+		t.Errorf("expected the grace to expire 6 days after collection, got %v", grace["expires"])
+	}
+	if _, ok := alice.ToMapStr()["files_grace"].(common.MapStr)["expires"]; ok {
+		t.Error("no expiry expected without a grace period")
+	}
+}
+
+func TestParseQuotaGrace(t *testing.T) {
+	cases := []struct {
+		s         string
+		state     string
+		remaining time.Duration
+	}{
+		{"none", GraceStateNone, 0},
+		{"", GraceStateNone, 0},
+		{"expired", GraceStateExpired, 0},
+		{"6%20days", GraceStateInGrace, 6 * 24 * time.Hour},
+		{"1 day", GraceStateInGrace, 24 * time.Hour},
+		{"2 hours", GraceStateInGrace, 2 * time.Hour},
+		{"30 mins", GraceStateInGrace, 30 * time.Minute},
+		{"1.5 hours", GraceStateInGrace, 90 * time.Minute},
+		{"soon", GraceStateUnknown, 0},
+		{"6 fortnights", GraceStateUnknown, 0},
+	}
+	for _, c := range cases {
+		g := ParseQuotaGrace(c.s)
+		if g.State() != c.state || g.Remaining() != c.remaining {
+			t.Errorf("ParseQuotaGrace(%q): expected %s/%s, got %s/%s", c.s, c.state, c.remaining, g.State(), g.Remaining())
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// MmVdiskRecoveryGroupInfo contains the information of a single recovery group from `mmvdisk recoverygroup list`
+type MmVdiskRecoveryGroupInfo struct {
+	version       int64
+	name          string
+	active        bool
+	currentServer string
+	nodeClass     string
+	servers       string
+	needsService  bool
+	remarks       string
+}
+
+// ToMapStr turns the recovery group information into a common.MapStr
+func (m *MmVdiskRecoveryGroupInfo) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"version":        m.version,
+		"recovery_group": m.name,
+		"active":         m.active,
+		"current_server": m.currentServer,
+		"node_class":     m.nodeClass,
+		"servers":        m.servers,
+		"needs_service":  m.needsService,
+		"remarks":        m.remarks,
+		"info_type":      "recoverygroup",
+	}
+}
+
+// UpdateDevice does not do anything, recovery groups do not belong to a device
+func (m *MmVdiskRecoveryGroupInfo) UpdateDevice(device string) {}
+
+// Name returns the name of the recovery group
+func (m *MmVdiskRecoveryGroupInfo) Name() string {
+	return m.name
+}
+
+// MmVdiskPdiskInfo contains the information of a single pdisk from `mmvdisk pdisk list`
+type MmVdiskPdiskInfo struct {
+	version          int64
+	recoveryGroup    string
+	declusteredArray string
+	name             string
+	location         string
+	userLocation     string
+	state            string
+	remarks          string
+}
+
+// ToMapStr turns the pdisk information into a common.MapStr
+func (m *MmVdiskPdiskInfo) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"version":           m.version,
+		"recovery_group":    m.recoveryGroup,
+		"declustered_array": m.declusteredArray,
+		"pdisk":             m.name,
+		"location":          m.location,
+		"user_location":     m.userLocation,
+		"state":             m.state,
+		"ok":                m.state == "ok",
+		"remarks":           m.remarks,
+		"info_type":         "pdisk",
+	}
+}
+
+// UpdateDevice does not do anything, pdisks do not belong to a device
+func (m *MmVdiskPdiskInfo) UpdateDevice(device string) {}
+
+func parseMmVdiskCallback(fields []string, fieldMap map[string]int) ParseResult {
+
+	var identifierFieldLocation = 1
+
+	switch fields[identifierFieldLocation] {
+	case "recoveryGroup":
+		return &MmVdiskRecoveryGroupInfo{
+			version:       parseOptionalInt(optionalField(fields, fieldMap, "version")),
+			name:          optionalField(fields, fieldMap, "recoveryGroup"),
+			active:        isYes(optionalField(fields, fieldMap, "active")),
+			currentServer: optionalField(fields, fieldMap, "currentServer"),
+			nodeClass:     optionalField(fields, fieldMap, "nodeClass"),
+			servers:       decodeGpfsString(optionalField(fields, fieldMap, "servers")),
+			needsService:  isYes(optionalField(fields, fieldMap, "needsService")),
+			remarks:       decodeGpfsString(optionalField(fields, fieldMap, "remarks")),
+		}
+	case "pdisk":
+		return &MmVdiskPdiskInfo{
+			version:          parseOptionalInt(optionalField(fields, fieldMap, "version")),
+			recoveryGroup:    optionalField(fields, fieldMap, "recoveryGroup"),
+			declusteredArray: optionalField(fields, fieldMap, "declusteredArray"),
+			name:             optionalField(fields, fieldMap, "pdisk"),
+			location:         decodeGpfsString(optionalField(fields, fieldMap, "location")),
+			userLocation:     decodeGpfsString(optionalField(fields, fieldMap, "userLocation")),
+			state:            decodeGpfsString(optionalField(fields, fieldMap, "state")),
+			remarks:          decodeGpfsString(optionalField(fields, fieldMap, "remarks")),
+		}
+	}
+	return nil
+}
+
+// ParseMmVdisk converts the lines of `mmvdisk recoverygroup list -Y` or `mmvdisk pdisk list -Y` into the desired information
+func ParseMmVdisk(output string) ([]ParseResult, error) {
+
+	var prefixFieldlocation = 0
+	var identifierFieldLocation = 1
+	var headerFieldLocation = 2
+
+	vs, _ := parseGpfsYOutput(prefixFieldlocation, identifierFieldLocation, headerFieldLocation, "mmvdisk", output, parseMmVdiskCallback)
+
+	var infos = make([]ParseResult, 0, len(vs))
+	for _, info := range vs {
+		if info == nil {
+			continue // line could not be parsed
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
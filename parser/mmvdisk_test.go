@@ -0,0 +1,60 @@
+//go:build !integration
+
+package parser
+
+import (
+	"testing"
+)
+
+func TestParseMmVdiskRecoveryGroups(t *testing.T) {
+	results, err := ParseMmVdisk(readFixture(t, "mmvdisk_recoverygroup.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 recovery groups, got %d", len(results))
+	}
+
+	rg, ok := results[1].(*MmVdiskRecoveryGroupInfo)
+	if !ok {
+		t.Fatalf("expected a recovery group, got %T", results[1])
+	}
+	expected := MmVdiskRecoveryGroupInfo{
+		version:       1,
+		name:          "rg2",
+		active:        true,
+		currentServer: "ess02",
+		nodeClass:     "ess_nc1",
+		servers:       "ess02,ess01",
+		needsService:  true,
+		remarks:       "pdisk replacement needed",
+	}
+	if *rg != expected {
+		t.Errorf("expected %+v, got %+v", expected, *rg)
+	}
+	if rg.Name() != "rg2" {
+		t.Errorf("expected name rg2, got %s", rg.Name())
+	}
+}
+
+func TestParseMmVdiskPdisks(t *testing.T) {
+	results, err := ParseMmVdisk(readFixture(t, "mmvdisk_pdisk.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 pdisk, got %d", len(results))
+	}
+
+	pdisk, ok := results[0].(*MmVdiskPdiskInfo)
+	if !ok {
+		t.Fatalf("expected a pdisk, got %T", results[0])
+	}
+	m := pdisk.ToMapStr()
+	if m["pdisk"] != "e1s05" || m["state"] != "missing/draining" || m["ok"] != false {
+		t.Errorf("unexpected pdisk information %v", map[string]interface{}(m))
+	}
+	if m["user_location"] != "Enclosure 1 Drawer 1 Slot 5" {
+		t.Errorf("expected the decoded user location, got %v", m["user_location"])
+	}
+}
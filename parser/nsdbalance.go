@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"math"
+	"sort"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// nsdFree is the free space of a single NSD or failure group
+type nsdFree struct {
+	name         string
+	failureGroup int64
+	nsds         int64
+	diskSize     int64
+	freeBlocks   int64
+}
+
+// freePercentage returns the free blocks as a percentage of the disk size, more precise than the rounded mmdf value
+func (n *nsdFree) freePercentage() float64 {
+	p, _ := percentage(n.freeBlocks, n.diskSize)
+	return p
+}
+
+// NSDBalanceInfo describes how evenly the free space is spread over the NSDs of a storage pool
+type NSDBalanceInfo struct {
+	device        string
+	poolName      string
+	threshold     float64
+	nsds          []nsdFree
+	failureGroups []nsdFree
+}
+
+// stats returns the minimum, maximum, mean and (population) standard deviation of the NSD free percentages
+func (b *NSDBalanceInfo) stats() (float64, float64, float64, float64) {
+	if len(b.nsds) == 0 {
+		return 0, 0, 0, 0
+	}
+	min, max := math.Inf(1), math.Inf(-1)
+	var sum float64
+	for i := range b.nsds {
+		p := b.nsds[i].freePercentage()
+		min = math.Min(min, p)
+		max = math.Max(max, p)
+		sum += p
+	}
+	mean := sum / float64(len(b.nsds))
+
+	var squares float64
+	for i := range b.nsds {
+		d := b.nsds[i].freePercentage() - mean
+		squares += d * d
+	}
+	return min, max, mean, math.Sqrt(squares / float64(len(b.nsds)))
+}
+
+// ToMapStr turns the balance analysis into a common.MapStr. NSDs whose free percentage differs more than the
+// threshold (in percentage points) from the pool mean are listed as deviating.
+func (b *NSDBalanceInfo) ToMapStr() common.MapStr {
+	min, max, mean, stddev := b.stats()
+
+	deviating := make([]common.MapStr, 0)
+	for i := range b.nsds {
+		n := &b.nsds[i]
+		if d := n.freePercentage() - mean; math.Abs(d) > b.threshold {
+			deviating = append(deviating, common.MapStr{
+				"nsd_name":        n.name,
+				"failure_group":   n.failureGroup,
+				"free_percentage": n.freePercentage(),
+				"deviation":       d,
+			})
+		}
+	}
+
+	failureGroups := make([]common.MapStr, 0, len(b.failureGroups))
+	for i := range b.failureGroups {
+		fg := &b.failureGroups[i]
+		failureGroups = append(failureGroups, common.MapStr{
+			"failure_group":   fg.failureGroup,
+			"nsds":            fg.nsds,
+			"disk_size":       fg.diskSize,
+			"free_blocks":     fg.freeBlocks,
+			"free_percentage": fg.freePercentage(),
+		})
+	}
+
+	return common.MapStr{
+		"device":                 b.device,
+		"pool_name":              b.poolName,
+		"nsds":                   len(b.nsds),
+		"free_percentage_min":    min,
+		"free_percentage_max":    max,
+		"free_percentage_mean":   mean,
+		"free_percentage_stddev": stddev,
+		"threshold":              b.threshold,
+		"deviating_nsds":         deviating,
+		"imbalanced":             len(deviating) > 0,
+		"failure_groups":         failureGroups,
+	}
+}
+
+// UpdateDevice sets the device name
+func (b *NSDBalanceInfo) UpdateDevice(device string) {
+	b.device = device
+}
+
+// AnalyzeNSDBalance builds a balance analysis for every storage pool from the nsd records of ParseMmDf
+func AnalyzeNSDBalance(results []ParseResult, threshold float64) []NSDBalanceInfo {
+	type poolKey struct {
+		device   string
+		poolName string
+	}
+
+	var keys []poolKey
+	pools := make(map[poolKey]*NSDBalanceInfo)
+	for _, r := range results {
+		nsd, ok := r.(*MmDfNSDInfo)
+		if !ok {
+			continue
+		}
+		key := poolKey{nsd.device, nsd.storagePool}
+		b, ok := pools[key]
+		if !ok {
+			b = &NSDBalanceInfo{device: nsd.device, poolName: nsd.storagePool, threshold: threshold}
+			pools[key] = b
+			keys = append(keys, key)
+		}
+		b.nsds = append(b.nsds, nsdFree{
+			name:         nsd.nsdname,
+			failureGroup: nsd.failureGroup,
+			nsds:         1,
+			diskSize:     nsd.diskSize,
+			freeBlocks:   nsd.freeBlocks,
+		})
+	}
+
+	var result = make([]NSDBalanceInfo, 0, len(keys))
+	for _, key := range keys {
+		b := pools[key]
+
+		groups := make(map[int64]*nsdFree)
+		for _, n := range b.nsds {
+			fg, ok := groups[n.failureGroup]
+			if !ok {
+				fg = &nsdFree{failureGroup: n.failureGroup}
+				groups[n.failureGroup] = fg
+			}
+			fg.nsds++
+			fg.diskSize += n.diskSize
+			fg.freeBlocks += n.freeBlocks
+		}
+		for _, fg := range groups {
+			b.failureGroups = append(b.failureGroups, *fg)
+		}
+		sort.Slice(b.failureGroups, func(i, j int) bool {
+			return b.failureGroups[i].failureGroup < b.failureGroups[j].failureGroup
+		})
+
+		result = append(result, *b)
+	}
+	return result
+}
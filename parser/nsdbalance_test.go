@@ -0,0 +1,57 @@
+//go:build !integration
+
+package parser
+
+import (
+	"math"
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestAnalyzeNSDBalance(t *testing.T) {
+	results, err := ParseMmDf("fs1", readFixture(t, "mmdf.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	balances := AnalyzeNSDBalance(results, 20)
+	if len(balances) != 2 {
+		t.Fatalf("expected the system and data pool, got %d", len(balances))
+	}
+
+	system := balances[0].ToMapStr()
+	if system["device"] != "fs1" || system["pool_name"] != "system" || system["nsds"] != 2 || system["imbalanced"] != false {
+		t.Errorf("unexpected system pool balance %v", map[string]interface{}(system))
+	}
+	if system["free_percentage_min"] != 37.5 || system["free_percentage_max"] != 50.0 || system["free_percentage_mean"] != 43.75 {
+		t.Errorf("expected the exact free percentages rather than the rounded mmdf ones, got %v", map[string]interface{}(system))
+	}
+
+	// d4 has 10% free against a mean of 40%
+	data := balances[1].ToMapStr()
+	if data["pool_name"] != "data" || data["imbalanced"] != true {
+		t.Fatalf("expected the data pool to be imbalanced, got %v", map[string]interface{}(data))
+	}
+	if stddev := data["free_percentage_stddev"].(float64); math.Abs(stddev-math.Sqrt(300)) > 1e-9 {
+		t.Errorf("expected a standard deviation of %f, got %f", math.Sqrt(300), stddev)
+	}
+	deviating := data["deviating_nsds"].([]common.MapStr)
+	if len(deviating) != 1 || deviating[0]["nsd_name"] != "d4" || deviating[0]["deviation"] != -30.0 || deviating[0]["failure_group"] != int64(2) {
+		t.Errorf("expected only d4 to deviate, got %v", deviating)
+	}
+
+	failureGroups := data["failure_groups"].([]common.MapStr)
+	if len(failureGroups) != 2 {
+		t.Fatalf("expected 2 failure groups, got %d", len(failureGroups))
+	}
+	for i, expected := range []float64{50, 30} {
+		fg := failureGroups[i]
+		if fg["failure_group"] != int64(i+1) || fg["nsds"] != int64(2) || fg["free_percentage"] != expected {
+			t.Errorf("failure group %d: expected %v%% free, got %v", i+1, expected, map[string]interface{}(fg))
+		}
+	}
+
+	if balances := AnalyzeNSDBalance(results, 35); balances[1].ToMapStr()["imbalanced"] != false {
+		t.Error("expected a balanced data pool with a larger threshold")
+	}
+}
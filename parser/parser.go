@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -25,6 +26,41 @@ func parseCertainInt(s string) int64 {
 	return v
 }
 
+// parseOptionalInt parses a string into an integer value, treating empty and "-" fields as zero
+func parseOptionalInt(s string) int64 {
+	if s == "" || s == "-" {
+		return 0
+	}
+	return parseCertainInt(s)
+}
+
+// optionalField returns the value of the named field, or an empty string if the header did not contain it
+func optionalField(fields []string, fieldMap map[string]int, name string) string {
+	i, ok := fieldMap[name]
+	if !ok || i >= len(fields) {
+		return ""
+	}
+	return fields[i]
+}
+
+// isYes returns true if the field holds one of the affirmative values GPFS uses
+func isYes(s string) bool {
+	switch strings.ToLower(s) {
+	case "yes", "y", "1", "true":
+		return true
+	}
+	return false
+}
+
+// decodeGpfsString undoes the percent encoding GPFS applies to values in -Y output
+func decodeGpfsString(s string) string {
+	d, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+	return d
+}
+
 // parseMmRepQuotaHeader builds a map of the field names and the corresponding index
 func parseGpfsHeaderFields(fields []string) (m map[string]int) {
 
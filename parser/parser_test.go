@@ -0,0 +1,19 @@
+//go:build !integration
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readFixture returns the content of a captured command output in testdata
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("cannot read fixture %s: %v", name, err)
+	}
+	return string(data)
+}
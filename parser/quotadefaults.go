@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// QuotaDefaultsInfo contains the default quota limits and the grace periods for a kind of quota in a filesystem or
+// fileset. An empty fileset means the settings apply to the whole filesystem.
+type QuotaDefaultsInfo struct {
+	filesystem       string
+	fileset          string
+	kind             string
+	hasLimits        bool
+	blockSoft        int64
+	blockHard        int64
+	filesSoft        int64
+	filesHard        int64
+	blockGracePeriod time.Duration
+	filesGracePeriod time.Duration
+}
+
+// ToMapStr turns the quota defaults into a common.MapStr
+func (q *QuotaDefaultsInfo) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"filesystem":                 q.filesystem,
+		"fileset":                    q.fileset,
+		"kind":                       q.kind,
+		"has_default_limits":         q.hasLimits,
+		"block_soft":                 q.blockSoft,
+		"block_hard":                 q.blockHard,
+		"files_soft":                 q.filesSoft,
+		"files_hard":                 q.filesHard,
+		"block_grace_period_seconds": int64(q.blockGracePeriod.Seconds()),
+		"files_grace_period_seconds": int64(q.filesGracePeriod.Seconds()),
+	}
+}
+
+// UpdateDevice sets the filesystem, in case the output did not mention it
+func (q *QuotaDefaultsInfo) UpdateDevice(device string) {
+	if q.filesystem == "" {
+		q.filesystem = device
+	}
+}
+
+// quotaDefaultsKey identifies the defaults that apply to a kind of quota in a filesystem or fileset
+type quotaDefaultsKey struct {
+	filesystem string
+	fileset    string
+	kind       string
+}
+
+// mmRepQuotaGrace represents a single line of `mmrepquota -t -Y` output
+type mmRepQuotaGrace struct {
+	filesystem  string
+	fileset     string
+	kind        string
+	blockPeriod time.Duration
+	filesPeriod time.Duration
+}
+
+func (m *mmRepQuotaGrace) ToMapStr() common.MapStr { return nil }
+func (m *mmRepQuotaGrace) UpdateDevice(string)     {}
+
+// parseGracePeriod converts a grace period setting such as "7 days" or a number of seconds into a duration
+func parseGracePeriod(s string) time.Duration {
+	s = strings.TrimSpace(decodeGpfsString(s))
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(v) * time.Second
+	}
+	g := ParseQuotaGrace(s)
+	if g.State() == GraceStateInGrace {
+		return g.Remaining()
+	}
+	return 0
+}
+
+// normaliseDefaultsFileset makes sure filesystem wide settings have an empty fileset
+func normaliseDefaultsFileset(kind string, fileset string) string {
+	if kind == "FILESET" || fileset == "root" || fileset == "-" {
+		return ""
+	}
+	return fileset
+}
+
+func parseMmRepQuotaGraceCallback(fields []string, fieldMap map[string]int) ParseResult {
+	blockPeriod := optionalField(fields, fieldMap, "blockGracePeriod")
+	if blockPeriod == "" {
+		blockPeriod = optionalField(fields, fieldMap, "blockGrace")
+	}
+	filesPeriod := optionalField(fields, fieldMap, "filesGracePeriod")
+	if filesPeriod == "" {
+		filesPeriod = optionalField(fields, fieldMap, "filesGrace")
+	}
+	kind := optionalField(fields, fieldMap, "quotaType")
+	return &mmRepQuotaGrace{
+		filesystem:  optionalField(fields, fieldMap, "filesystemName"),
+		fileset:     normaliseDefaultsFileset(kind, optionalField(fields, fieldMap, "filesetname")),
+		kind:        kind,
+		blockPeriod: parseGracePeriod(blockPeriod),
+		filesPeriod: parseGracePeriod(filesPeriod),
+	}
+}
+
+// ParseMmRepQuotaDefaults combines the output of `mmrepquota -d -Y` (the default limits) and
+// `mmrepquota -t -Y` (the grace periods) into the default settings per filesystem, fileset and kind of quota
+func ParseMmRepQuotaDefaults(device string, defaultsOutput string, graceOutput string) ([]QuotaDefaultsInfo, error) {
+
+	var prefixFieldlocation = 0
+	var identifierFieldLocation = 1
+	var headerFieldLocation = 2
+
+	var keys []quotaDefaultsKey
+	defaults := make(map[quotaDefaultsKey]*QuotaDefaultsInfo)
+	lookup := func(key quotaDefaultsKey) *QuotaDefaultsInfo {
+		d, ok := defaults[key]
+		if !ok {
+			d = &QuotaDefaultsInfo{filesystem: key.filesystem, fileset: key.fileset, kind: key.kind}
+			d.UpdateDevice(device)
+			defaults[key] = d
+			keys = append(keys, key)
+		}
+		return d
+	}
+
+	ds, _ := parseGpfsYOutput(prefixFieldlocation, identifierFieldLocation, headerFieldLocation, "mmrepquota", defaultsOutput, parseMmRepQuotaCallback)
+	for _, q := range ds {
+		qi := q.(*QuotaInfo)
+		d := lookup(quotaDefaultsKey{qi.filesystem, normaliseDefaultsFileset(qi.kind, qi.fileset), qi.kind})
+		d.hasLimits = true
+		d.blockSoft = qi.blockSoft
+		d.blockHard = qi.blockHard
+		d.filesSoft = qi.filesSoft
+		d.filesHard = qi.filesHard
+	}
+
+	gs, _ := parseGpfsYOutput(prefixFieldlocation, identifierFieldLocation, headerFieldLocation, "mmrepquota", graceOutput, parseMmRepQuotaGraceCallback)
+	for _, g := range gs {
+		gi := g.(*mmRepQuotaGrace)
+		d := lookup(quotaDefaultsKey{gi.filesystem, gi.fileset, gi.kind})
+		d.blockGracePeriod = gi.blockPeriod
+		d.filesGracePeriod = gi.filesPeriod
+	}
+
+	var infos = make([]QuotaDefaultsInfo, 0, len(keys))
+	for _, key := range keys {
+		infos = append(infos, *defaults[key])
+	}
+
+	return infos, nil
+}
+
+// FlagQuotaDefaults marks the quota entries that inherit their limits from the defaults. When the remarks of
+// mmrepquota tell us the entry type we use that, otherwise we compare the limits with the applicable defaults.
+func FlagQuotaDefaults(quotas []QuotaInfo, defaults []QuotaDefaultsInfo) {
+	byKey := make(map[quotaDefaultsKey]*QuotaDefaultsInfo)
+	for i := range defaults {
+		d := &defaults[i]
+		byKey[quotaDefaultsKey{d.filesystem, d.fileset, d.kind}] = d
+	}
+
+	for i := range quotas {
+		q := &quotas[i]
+		if q.remarks != "" {
+			q.usesDefault = strings.HasPrefix(q.remarks, "d_")
+			continue
+		}
+		d, ok := byKey[quotaDefaultsKey{q.filesystem, normaliseDefaultsFileset(q.kind, q.fileset), q.kind}]
+		if !ok {
+			d, ok = byKey[quotaDefaultsKey{q.filesystem, "", q.kind}]
+		}
+		q.usesDefault = ok && d.hasLimits &&
+			q.blockSoft == d.blockSoft && q.blockHard == d.blockHard &&
+			q.filesSoft == d.filesSoft && q.filesHard == d.filesHard
+	}
+}
@@ -0,0 +1,95 @@
+//go:build !integration
+
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMmRepQuotaDefaults(t *testing.T) {
+	defaults, err := ParseMmRepQuotaDefaults("fs1", readFixture(t, "mmrepquota_defaults.txt"), readFixture(t, "mmrepquota_grace.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []QuotaDefaultsInfo{
+		{filesystem: "fs1", fileset: "data", kind: "USR", hasLimits: true, blockSoft: 500, blockHard: 1000},
+		{filesystem: "fs1", kind: "GRP", hasLimits: true, filesSoft: 100, filesHard: 200,
+			blockGracePeriod: 3 * 24 * time.Hour, filesGracePeriod: time.Hour},
+		{filesystem: "fs1", kind: "USR", blockGracePeriod: 7 * 24 * time.Hour, filesGracePeriod: 24 * time.Hour},
+		{filesystem: "fs1", kind: "FILESET", blockGracePeriod: 24 * time.Hour, filesGracePeriod: 24 * time.Hour},
+	}
+	if len(defaults) != len(expected) {
+		t.Fatalf("expected %d defaults, got %d: %+v", len(expected), len(defaults), defaults)
+	}
+	for i := range expected {
+		if defaults[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], defaults[i])
+		}
+	}
+}
+
+// withoutRemarks drops the remarks column from mmrepquota -Y output, like older releases print it
+func withoutRemarks(output string) string {
+	lines := strings.Split(output, "\n")
+	column := -1
+	for i, name := range strings.Split(lines[0], ":") {
+		if name == "remarks" {
+			column = i
+		}
+	}
+	for i, line := range lines {
+		if fields := strings.Split(line, ":"); len(fields) > column {
+			lines[i] = strings.Join(append(fields[:column], fields[column+1:]...), ":")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestFlagQuotaDefaults(t *testing.T) {
+	defaults, err := ParseMmRepQuotaDefaults("fs1", readFixture(t, "mmrepquota_defaults.txt"), readFixture(t, "mmrepquota_grace.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		output   string
+		expected map[[2]string]bool
+	}{
+		{"remarks", readFixture(t, "mmrepquota.txt"), map[[2]string]bool{
+			{"USR", "alice"}:    false,
+			{"USR", "bob"}:      true,
+			{"USR", "carol"}:    false, // explicit limits that happen to equal the defaults
+			{"USR", "1002"}:     true,
+			{"GRP", "users"}:    true,
+			{"FILESET", "data"}: false,
+		}},
+		{"limits", withoutRemarks(readFixture(t, "mmrepquota.txt")), map[[2]string]bool{
+			{"USR", "alice"}:    false, // own files limits
+			{"USR", "bob"}:      true,
+			{"USR", "carol"}:    true,
+			{"USR", "1002"}:     true,
+			{"GRP", "users"}:    true, // falls back to the filesystem wide default
+			{"FILESET", "data"}: false,
+		}},
+	}
+	for _, test := range tests {
+		quotas, err := ParseMmRepQuota(test.output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		FlagQuotaDefaults(quotas, defaults)
+		byEntity := quotaByEntity(t, quotas)
+		if len(byEntity) != len(test.expected) {
+			t.Fatalf("%s: expected %d entries, got %d", test.name, len(test.expected), len(byEntity))
+		}
+		for key, q := range byEntity {
+			if q.usesDefault != test.expected[key] {
+				t.Errorf("%s: %s %s: expected uses_default %t", test.name, key[0], key[1], test.expected[key])
+			}
+		}
+	}
+}
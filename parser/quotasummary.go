@@ -0,0 +1,236 @@
+package parser
+
+import (
+	"sort"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// QuotaEntitySummary aggregates the USR or GRP quota entries of a single entity over all filesets and filesystems
+type QuotaEntitySummary struct {
+	kind              string
+	entityID          string
+	entityName        string
+	entries           int64
+	blockUsage        int64
+	filesUsage        int64
+	filesetsOverSoft  int64
+	mostConstrained   *QuotaInfo
+	mostConstrainedPc float64
+}
+
+// ToMapStr turns the summary into a common.MapStr
+func (s *QuotaEntitySummary) ToMapStr() common.MapStr {
+	m := common.MapStr{
+		"kind": s.kind,
+		"entity": common.MapStr{
+			"id":   s.entityID,
+			"name": s.entityName,
+		},
+		"entries":            s.entries,
+		"block_usage":        s.blockUsage,
+		"files_usage":        s.filesUsage,
+		"filesets_over_soft": s.filesetsOverSoft,
+	}
+	if s.mostConstrained != nil {
+		m["most_constrained"] = common.MapStr{
+			"filesystem": s.mostConstrained.filesystem,
+			"fileset":    s.mostConstrained.fileset,
+			"percentage": s.mostConstrainedPc,
+			"state":      s.mostConstrained.State(),
+		}
+	}
+	return m
+}
+
+// UpdateDevice does not do anything, a summary spans all devices
+func (s *QuotaEntitySummary) UpdateDevice(device string) {}
+
+// limitPercentage returns the highest usage percentage of the entry relative to its tightest limits
+func (q *QuotaInfo) limitPercentage() (float64, bool) {
+	var highest float64
+	var limited bool
+	for _, c := range []struct{ usage, soft, hard int64 }{
+		{q.blockUsage, q.blockSoft, q.blockHard},
+		{q.filesUsage, q.filesSoft, q.filesHard},
+	} {
+		limit := c.soft
+		if limit <= 0 {
+			limit = c.hard
+		}
+		if p, ok := percentage(c.usage, limit); ok {
+			limited = true
+			if p > highest {
+				highest = p
+			}
+		}
+	}
+	return highest, limited
+}
+
+// overSoft returns true if the block or files usage exceeds the soft limit
+func (q *QuotaInfo) overSoft() bool {
+	return (q.blockSoft > 0 && q.blockUsage > q.blockSoft) || (q.filesSoft > 0 && q.filesUsage > q.filesSoft)
+}
+
+// AggregateQuotaPerEntity builds a summary per user and group from the quota entries of all devices
+func AggregateQuotaPerEntity(quotas []QuotaInfo) []QuotaEntitySummary {
+	type entityKey struct {
+		kind   string
+		entity string
+	}
+
+	var keys []entityKey
+	summaries := make(map[entityKey]*QuotaEntitySummary)
+	for i := range quotas {
+		q := &quotas[i]
+		if q.kind != "USR" && q.kind != "GRP" {
+			continue
+		}
+
+		key := entityKey{q.kind, q.entity}
+		s, ok := summaries[key]
+		if !ok {
+			s = &QuotaEntitySummary{kind: q.kind, entityID: q.entityID, entityName: q.entityName}
+			summaries[key] = s
+			keys = append(keys, key)
+		}
+
+		s.entries++
+		s.blockUsage += q.blockUsage
+		s.filesUsage += q.filesUsage
+		if q.overSoft() {
+			s.filesetsOverSoft++
+		}
+		if p, ok := q.limitPercentage(); ok && (s.mostConstrained == nil || p > s.mostConstrainedPc) {
+			s.mostConstrained = q
+			s.mostConstrainedPc = p
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].kind < keys[j].kind || (keys[i].kind == keys[j].kind && keys[i].entity < keys[j].entity)
+	})
+
+	var result = make([]QuotaEntitySummary, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *summaries[key])
+	}
+	return result
+}
+
+// QuotaFilesetTopN lists the users consuming most of a fileset, by block usage and by number of files
+type QuotaFilesetTopN struct {
+	filesystem string
+	fileset    string
+	limits     *QuotaInfo // the FILESET quota entry, if there is one
+	byBlocks   []*QuotaInfo
+	byFiles    []*QuotaInfo
+}
+
+// share returns the part of the fileset usage and limit (soft, or hard if there is no soft limit) that the usage
+// represents, in percent
+func share(usage int64, filesetUsage int64, soft int64, hard int64) common.MapStr {
+	m := common.MapStr{}
+	if p, ok := percentage(usage, filesetUsage); ok {
+		m["usage_percentage"] = p
+	}
+	limit := soft
+	if limit <= 0 {
+		limit = hard
+	}
+	if p, ok := percentage(usage, limit); ok {
+		m["limit_percentage"] = p
+	}
+	return m
+}
+
+// ToMapStr turns the top N lists into a common.MapStr
+func (t *QuotaFilesetTopN) ToMapStr() common.MapStr {
+	fs := QuotaInfo{}
+	if t.limits != nil {
+		fs = *t.limits
+	}
+
+	byBlocks := make([]common.MapStr, 0, len(t.byBlocks))
+	for _, q := range t.byBlocks {
+		byBlocks = append(byBlocks, common.MapStr{
+			"entity":      q.entityMapStr(),
+			"block_usage": q.blockUsage,
+			"share":       share(q.blockUsage, fs.blockUsage, fs.blockSoft, fs.blockHard),
+		})
+	}
+	byFiles := make([]common.MapStr, 0, len(t.byFiles))
+	for _, q := range t.byFiles {
+		byFiles = append(byFiles, common.MapStr{
+			"entity":      q.entityMapStr(),
+			"files_usage": q.filesUsage,
+			"share":       share(q.filesUsage, fs.filesUsage, fs.filesSoft, fs.filesHard),
+		})
+	}
+
+	return common.MapStr{
+		"filesystem":    t.filesystem,
+		"fileset":       t.fileset,
+		"block_usage":   fs.blockUsage,
+		"files_usage":   fs.filesUsage,
+		"top_by_blocks": byBlocks,
+		"top_by_files":  byFiles,
+	}
+}
+
+// UpdateDevice does not do anything, since we already have that information
+func (t *QuotaFilesetTopN) UpdateDevice(device string) {}
+
+// topN returns the n entries with the largest value, largest first
+func topN(entries []*QuotaInfo, n int, value func(*QuotaInfo) int64) []*QuotaInfo {
+	sorted := make([]*QuotaInfo, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return value(sorted[i]) > value(sorted[j]) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// TopQuotaConsumersPerFileset returns, per fileset, the n users with the highest block usage and the n users with
+// the most files
+func TopQuotaConsumersPerFileset(quotas []QuotaInfo, n int) []QuotaFilesetTopN {
+	type filesetKey struct {
+		filesystem string
+		fileset    string
+	}
+
+	var keys []filesetKey
+	users := make(map[filesetKey][]*QuotaInfo)
+	limits := make(map[filesetKey]*QuotaInfo)
+	for i := range quotas {
+		q := &quotas[i]
+		key := filesetKey{q.filesystem, q.fileset}
+		if _, ok := users[key]; !ok {
+			keys = append(keys, key)
+			users[key] = nil
+		}
+		switch q.kind {
+		case "USR":
+			users[key] = append(users[key], q)
+		case "FILESET":
+			limits[key] = q
+		}
+	}
+
+	var result = make([]QuotaFilesetTopN, 0, len(keys))
+	for _, key := range keys {
+		if len(users[key]) == 0 {
+			continue // no user quota in this fileset
+		}
+		result = append(result, QuotaFilesetTopN{
+			filesystem: key.filesystem,
+			fileset:    key.fileset,
+			limits:     limits[key],
+			byBlocks:   topN(users[key], n, func(q *QuotaInfo) int64 { return q.blockUsage }),
+			byFiles:    topN(users[key], n, func(q *QuotaInfo) int64 { return q.filesUsage }),
+		})
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+//go:build !integration
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestAggregateQuotaPerEntity(t *testing.T) {
+	quotas, err := ParseMmRepQuota(readFixture(t, "mmrepquota_filesets.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries := AggregateQuotaPerEntity(quotas)
+	if len(summaries) != 3 {
+		t.Fatalf("expected 3 entities, got %d", len(summaries))
+	}
+
+	users, alice, bob := summaries[0], summaries[1], summaries[2]
+	if users.kind != "GRP" || alice.entityName != "alice" || bob.entityName != "bob" {
+		t.Fatalf("unexpected order: %s %s, %s, %s", users.kind, users.entityName, alice.entityName, bob.entityName)
+	}
+
+	if alice.entries != 3 || alice.blockUsage != 750 || alice.filesUsage != 165 || alice.filesetsOverSoft != 2 {
+		t.Errorf("unexpected totals for alice: %+v", alice)
+	}
+	m := alice.ToMapStr()
+	if e := m["entity"].(common.MapStr); e["id"] != "1000" || e["name"] != "alice" {
+		t.Errorf("unexpected entity %v", map[string]interface{}(e))
+	}
+	constrained := m["most_constrained"].(common.MapStr)
+	if constrained["fileset"] != "home" || constrained["percentage"] != 150.0 || constrained["state"] != QuotaStateInGrace {
+		t.Errorf("expected the files quota in home to be the most constrained, got %v", map[string]interface{}(constrained))
+	}
+
+	// without a soft limit, the hard limit counts
+	if c := bob.ToMapStr()["most_constrained"].(common.MapStr); c["percentage"] != 30.0 {
+		t.Errorf("expected 30%% of the hard limit for bob, got %v", c["percentage"])
+	}
+	if _, ok := users.ToMapStr()["most_constrained"]; ok {
+		t.Error("an entity without limits cannot be constrained")
+	}
+}
+
+func TestTopQuotaConsumersPerFileset(t *testing.T) {
+	quotas, err := ParseMmRepQuota(readFixture(t, "mmrepquota_filesets.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tops := TopQuotaConsumersPerFileset(quotas, 1)
+	if len(tops) != 3 {
+		t.Fatalf("expected 3 filesets with user quota, got %d", len(tops))
+	}
+
+	data := tops[0].ToMapStr()
+	if data["filesystem"] != "fs1" || data["fileset"] != "data" || data["block_usage"] != int64(1000) {
+		t.Fatalf("unexpected first fileset %v", map[string]interface{}(data))
+	}
+	byBlocks := data["top_by_blocks"].([]common.MapStr)
+	byFiles := data["top_by_files"].([]common.MapStr)
+	if len(byBlocks) != 1 || len(byFiles) != 1 {
+		t.Fatalf("expected a single user per list, got %d and %d", len(byBlocks), len(byFiles))
+	}
+	if byBlocks[0]["entity"].(common.MapStr)["name"] != "alice" || byFiles[0]["entity"].(common.MapStr)["name"] != "bob" {
+		t.Errorf("expected alice by blocks and bob by files")
+	}
+	if s := byBlocks[0]["share"].(common.MapStr); s["usage_percentage"] != 60.0 || s["limit_percentage"] != 30.0 {
+		t.Errorf("unexpected block share %v", map[string]interface{}(s))
+	}
+	// the files limit only has a hard limit
+	if s := byFiles[0]["share"].(common.MapStr); s["usage_percentage"] != 10.0 || s["limit_percentage"] != 2.0 {
+		t.Errorf("unexpected files share %v", map[string]interface{}(s))
+	}
+
+	// without a FILESET entry, there is nothing to compare with
+	scratch := tops[2].ToMapStr()
+	if scratch["fileset"] != "scratch" || len(scratch["top_by_blocks"].([]common.MapStr)[0]["share"].(common.MapStr)) != 0 {
+		t.Errorf("expected no shares in scratch, got %v", map[string]interface{}(scratch))
+	}
+}
@@ -0,0 +1,146 @@
+// Package report builds the periodic reports that are generated from the collected information, rather than
+// published as events
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hpcugent/gpfsbeat/metadata"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+// Usage measures for the chargeback report
+const (
+	UsageAverage   = "average"
+	UsageByteHours = "byte-hours"
+)
+
+// ChargebackRow contains the storage usage of a single project over the reporting period
+type ChargebackRow struct {
+	Project      string
+	PI           string
+	Department   string
+	CostCenter   string
+	Filesets     int
+	AverageBytes float64
+	AverageFiles float64
+	ByteHours    float64
+}
+
+// Chargeback groups the FILESET quota usage by project. Each element of samples holds the quota entries of a single
+// mmrepquota run; the average usage over the samples is charged for the whole period, so byte-hours is the average
+// usage times the length of the period. The filesets provide the junction paths to match on a path prefix, filesets
+// without a project are reported with an empty project.
+func Chargeback(samples [][]parser.QuotaInfo, filesets []parser.MmLsFilesetInfo, m *metadata.Source, period time.Duration) []ChargebackRow {
+	paths := make(map[[2]string]string, len(filesets))
+	for i := range filesets {
+		f := &filesets[i]
+		paths[[2]string{f.Filesystem(), f.Name()}] = f.Path()
+	}
+
+	// total usage per fileset over all samples
+	blocks := make(map[[2]string]int64)
+	files := make(map[[2]string]int64)
+	for _, quotas := range samples {
+		for i := range quotas {
+			q := &quotas[i]
+			if q.Kind() != "FILESET" {
+				continue
+			}
+			key := [2]string{q.Filesystem(), q.Fileset()}
+			blocks[key] += q.BlockUsage()
+			files[key] += q.FilesUsage()
+		}
+	}
+
+	rows := make(map[metadata.Entry]*ChargebackRow)
+	n := float64(len(samples))
+	for key, kib := range blocks {
+		var e metadata.Entry
+		if m != nil {
+			e, _ = m.Lookup(key[0], key[1], paths[key])
+		}
+		// filesets of the same project may match different entries, we only group on the reported columns
+		e = metadata.Entry{Project: e.Project, PI: e.PI, Department: e.Department, CostCenter: e.CostCenter}
+
+		row, ok := rows[e]
+		if !ok {
+			row = &ChargebackRow{Project: e.Project, PI: e.PI, Department: e.Department, CostCenter: e.CostCenter}
+			rows[e] = row
+		}
+		row.Filesets++
+		row.AverageBytes += float64(kib) * 1024 / n
+		row.AverageFiles += float64(files[key]) / n
+	}
+
+	var result = make([]ChargebackRow, 0, len(rows))
+	for _, row := range rows {
+		row.ByteHours = row.AverageBytes * period.Hours()
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Project != result[j].Project {
+			return result[i].Project < result[j].Project
+		}
+		return result[i].CostCenter < result[j].CostCenter
+	})
+	return result
+}
+
+// fields returns the column names and values of the row for the requested usage measure
+func (r *ChargebackRow) fields(usage string) ([]string, []interface{}) {
+	names := []string{"project", "pi", "department", "cost_center", "filesets", "average_files"}
+	values := []interface{}{r.Project, r.PI, r.Department, r.CostCenter, r.Filesets, r.AverageFiles}
+	if usage == UsageByteHours {
+		return append(names, "byte_hours"), append(values, r.ByteHours)
+	}
+	return append(names, "average_bytes"), append(values, r.AverageBytes)
+}
+
+// WriteCSV writes the rows as CSV with a header line
+func WriteCSV(w io.Writer, rows []ChargebackRow, usage string) error {
+	writer := csv.NewWriter(w)
+	header, _ := (&ChargebackRow{}).fields(usage)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for i := range rows {
+		_, values := rows[i].fields(usage)
+		record := make([]string, len(values))
+		for j, v := range values {
+			switch v := v.(type) {
+			case float64:
+				record[j] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				record[j] = fmt.Sprint(v)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the rows as a JSON array
+func WriteJSON(w io.Writer, rows []ChargebackRow, usage string) error {
+	objects := make([]map[string]interface{}, 0, len(rows))
+	for i := range rows {
+		names, values := rows[i].fields(usage)
+		o := make(map[string]interface{}, len(names))
+		for j, name := range names {
+			o[name] = values[j]
+		}
+		objects = append(objects, o)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(objects)
+}
@@ -0,0 +1,94 @@
+//go:build !integration
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hpcugent/gpfsbeat/metadata"
+	"github.com/hpcugent/gpfsbeat/parser"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func testChargeback(t *testing.T) []ChargebackRow {
+	t.Helper()
+	var samples [][]parser.QuotaInfo
+	for _, name := range []string{"mmrepquota_1.txt", "mmrepquota_2.txt"} {
+		quotas, err := parser.ParseMmRepQuota(readFixture(t, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, quotas)
+	}
+	filesets, err := parser.ParseMmLsFileset("", readFixture(t, "mmlsfileset.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := metadata.New(filepath.Join("testdata", "metadata.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Chargeback(samples, filesets, m, 10*time.Hour)
+}
+
+func TestChargeback(t *testing.T) {
+	rows := testChargeback(t)
+
+	// projA matches on its name and projB on its junction path, scratch has no project
+	expected := []ChargebackRow{
+		{Filesets: 1, AverageBytes: 1000 * 1024, AverageFiles: 10, ByteHours: 10 * 1000 * 1024},
+		{Project: "Project A", PI: "alice", Department: "physics", CostCenter: "CC100", Filesets: 2,
+			AverageBytes: 5000 * 1024, AverageFiles: 50, ByteHours: 10 * 5000 * 1024},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %+v", len(expected), rows)
+	}
+	for i := range expected {
+		if rows[i] != expected[i] {
+			t.Errorf("row %d: expected %+v, got %+v", i, expected[i], rows[i])
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	rows := testChargeback(t)
+
+	var csv bytes.Buffer
+	if err := WriteCSV(&csv, rows, UsageByteHours); err != nil {
+		t.Fatal(err)
+	}
+	expected := "project,pi,department,cost_center,filesets,average_files,byte_hours\n" +
+		",,,,1,10,10240000\n" +
+		"Project A,alice,physics,CC100,2,50,51200000\n"
+	if csv.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, csv.String())
+	}
+
+	var out bytes.Buffer
+	if err := WriteJSON(&out, rows, UsageAverage); err != nil {
+		t.Fatal(err)
+	}
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &objects); err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[1]["project"] != "Project A" || objects[1]["average_bytes"] != float64(5000*1024) {
+		t.Errorf("unexpected JSON report %v", objects)
+	}
+	if _, ok := objects[1]["byte_hours"]; ok {
+		t.Error("expected only the requested usage measure")
+	}
+}
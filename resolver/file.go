@@ -0,0 +1,58 @@
+package resolver
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// fileResolver uses a static mapping file with lines of the form `kind,id,name`, e.g., `USR,2001,alice`
+type fileResolver struct {
+	names map[string]string
+	ids   map[string]string
+}
+
+func newFileResolver(path string) (*fileResolver, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open resolver mapping file: %v", err)
+	}
+	defer f.Close()
+
+	r := &fileResolver{
+		names: make(map[string]string),
+		ids:   make(map[string]string),
+	}
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse resolver mapping file %s: %v", path, err)
+		}
+		kind := strings.ToUpper(strings.TrimSpace(record[0]))
+		id := strings.TrimSpace(record[1])
+		name := strings.TrimSpace(record[2])
+		r.names[kind+":"+id] = name
+		r.ids[kind+":"+name] = id
+	}
+	return r, nil
+}
+
+func (r *fileResolver) Name(kind string, id string) (string, bool) {
+	name, ok := r.names[kind+":"+id]
+	return name, ok
+}
+
+func (r *fileResolver) ID(kind string, name string) (string, bool) {
+	id, ok := r.ids[kind+":"+name]
+	return id, ok
+}
@@ -0,0 +1,114 @@
+package resolver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/hpcugent/gpfsbeat/config"
+)
+
+var ldapsearchTimeout = 10 * time.Second
+
+// ldapResolver queries an LDAP directory with posixAccount and posixGroup entries through ldapsearch
+type ldapResolver struct {
+	config config.LDAPConfig
+}
+
+// ldapEscape escapes the special characters in a value used in a search filter (RFC 4515)
+func ldapEscape(s string) string {
+	return strings.NewReplacer(`\`, `\5c`, `*`, `\2a`, `(`, `\28`, `)`, `\29`, "\x00", `\00`).Replace(s)
+}
+
+// attributes returns the name and ID attributes and the object class for the kind of entity
+func (r *ldapResolver) attributes(kind string) (string, string, string) {
+	if kind == Group {
+		return "cn", "gidNumber", "posixGroup"
+	}
+	return "uid", "uidNumber", "posixAccount"
+}
+
+// passwordFile writes the bind password to a file only we can read, so it does not show up in the process list.
+// The caller removes the file.
+func (r *ldapResolver) passwordFile() (string, error) {
+	f, err := os.CreateTemp("", "gpfsbeat-ldap-")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// ldapsearch uses the complete content as the password, so no trailing newline
+	if _, err := f.WriteString(r.config.BindPassword); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// search runs ldapsearch with the filter and returns the value of the requested attribute in the first entry
+func (r *ldapResolver) search(filter string, attribute string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), ldapsearchTimeout)
+	defer cancel()
+
+	args := []string{"-x", "-LLL", "-H", r.config.URI, "-b", r.config.BaseDN, "-z", "1"}
+	if r.config.BindDN != "" {
+		passwordFile, err := r.passwordFile()
+		if err != nil {
+			logp.Err("Cannot pass the LDAP bind password to %s. Error: %s", r.config.LdapSearchCommand, err)
+			return "", false
+		}
+		defer os.Remove(passwordFile)
+		args = append(args, "-D", r.config.BindDN, "-y", passwordFile)
+	}
+	args = append(args, filter, attribute)
+
+	cmd := exec.CommandContext(ctx, r.config.LdapSearchCommand, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	if err := cmd.Run(); err != nil {
+		// ldapsearch exits with 4 when the size limit is hit, which still gives us the first entry
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 4 {
+			logp.Err("Command %s did not run correctly for filter %s! Error: %s", r.config.LdapSearchCommand, filter, err)
+			return "", false
+		}
+	}
+
+	prefix := strings.ToLower(attribute) + ":"
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(strings.ToLower(line), prefix) {
+			continue
+		}
+		value := line[len(prefix):]
+		// LDIF base64 encodes values that are not safe strings, e.g., with non-ASCII characters, as `attr:: <base64>`
+		if strings.HasPrefix(value, ":") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+			if err != nil {
+				logp.Err("Cannot decode the %s value returned for filter %s. Error: %s", attribute, filter, err)
+				return "", false
+			}
+			return string(decoded), true
+		}
+		return strings.TrimSpace(value), true
+	}
+	return "", false
+}
+
+func (r *ldapResolver) Name(kind string, id string) (string, bool) {
+	nameAttr, idAttr, class := r.attributes(kind)
+	return r.search(fmt.Sprintf("(&(objectClass=%s)(%s=%s))", class, idAttr, ldapEscape(id)), nameAttr)
+}
+
+func (r *ldapResolver) ID(kind string, name string) (string, bool) {
+	nameAttr, idAttr, class := r.attributes(kind)
+	return r.search(fmt.Sprintf("(&(objectClass=%s)(%s=%s))", class, nameAttr, ldapEscape(name)), idAttr)
+}
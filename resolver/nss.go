@@ -0,0 +1,52 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var getentTimeout = 10 * time.Second
+
+// nssResolver uses getent, so we see the same users and groups as the rest of the system (including sssd, etc.)
+type nssResolver struct {
+	command string
+}
+
+// getent returns the name and ID of the entry matching the key, which can be either a name or an ID
+func (r *nssResolver) getent(kind string, key string) (string, string, bool) {
+	database := "passwd"
+	if kind == Group {
+		database = "group"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), getentTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.command, database, key)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	if err := cmd.Run(); err != nil {
+		return "", "", false // getent exits with 2 when the key is unknown
+	}
+
+	// both passwd and group entries have the name in the first and the ID in the third field
+	fields := strings.Split(strings.TrimSpace(out.String()), ":")
+	if len(fields) < 3 {
+		return "", "", false
+	}
+	return fields[0], fields[2], true
+}
+
+func (r *nssResolver) Name(kind string, id string) (string, bool) {
+	name, _, ok := r.getent(kind, id)
+	return name, ok
+}
+
+func (r *nssResolver) ID(kind string, name string) (string, bool) {
+	_, id, ok := r.getent(kind, name)
+	return id, ok
+}
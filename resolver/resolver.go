@@ -0,0 +1,123 @@
+// Package resolver maps the numeric user and group IDs reported by GPFS to names and back
+package resolver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hpcugent/gpfsbeat/config"
+)
+
+// Kinds of entities we can resolve, these match the quota types used by mmrepquota
+const (
+	User  = "USR"
+	Group = "GRP"
+)
+
+// Resolver maps IDs to names and names to IDs for users and groups
+type Resolver interface {
+	// Name returns the name belonging to the numeric ID
+	Name(kind string, id string) (string, bool)
+	// ID returns the numeric ID belonging to the name
+	ID(kind string, name string) (string, bool)
+}
+
+// New builds the resolver described by the configuration: the sources are tried in the given order and the
+// results are cached
+func New(c config.ResolverConfig) (Resolver, error) {
+	var chain chainResolver
+	for _, source := range c.Sources {
+		switch source {
+		case "nss":
+			chain = append(chain, &nssResolver{command: c.GetentCommand})
+		case "file":
+			r, err := newFileResolver(c.MappingFile)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, r)
+		case "ldap":
+			chain = append(chain, &ldapResolver{config: c.LDAP})
+		default:
+			return nil, fmt.Errorf("unknown resolver source %s", source)
+		}
+	}
+	return newCachingResolver(chain, c.TTL), nil
+}
+
+// chainResolver asks each resolver in turn until one of them knows the answer
+type chainResolver []Resolver
+
+func (c chainResolver) Name(kind string, id string) (string, bool) {
+	for _, r := range c {
+		if name, ok := r.Name(kind, id); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (c chainResolver) ID(kind string, name string) (string, bool) {
+	for _, r := range c {
+		if id, ok := r.ID(kind, name); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// cacheEntry holds a (possibly negative) resolution result
+type cacheEntry struct {
+	value   string
+	ok      bool
+	expires time.Time
+}
+
+// cachingResolver remembers the results of the underlying resolver for a while
+type cachingResolver struct {
+	resolver Resolver
+	ttl      time.Duration
+	now      func() time.Time
+
+	mutex sync.Mutex
+	names map[string]cacheEntry
+	ids   map[string]cacheEntry
+}
+
+func newCachingResolver(r Resolver, ttl time.Duration) *cachingResolver {
+	return &cachingResolver{
+		resolver: r,
+		ttl:      ttl,
+		now:      time.Now,
+		names:    make(map[string]cacheEntry),
+		ids:      make(map[string]cacheEntry),
+	}
+}
+
+// lookup returns the cached result for the key, or calls fn and caches its result
+func (c *cachingResolver) lookup(cache map[string]cacheEntry, key string, fn func() (string, bool)) (string, bool) {
+	now := c.now()
+
+	c.mutex.Lock()
+	e, found := cache[key]
+	c.mutex.Unlock()
+	if found && now.Before(e.expires) {
+		return e.value, e.ok
+	}
+
+	value, ok := fn()
+
+	c.mutex.Lock()
+	cache[key] = cacheEntry{value: value, ok: ok, expires: now.Add(c.ttl)}
+	c.mutex.Unlock()
+	return value, ok
+}
+
+func (c *cachingResolver) Name(kind string, id string) (string, bool) {
+	return c.lookup(c.names, kind+":"+id, func() (string, bool) { return c.resolver.Name(kind, id) })
+}
+
+func (c *cachingResolver) ID(kind string, name string) (string, bool) {
+	return c.lookup(c.ids, kind+":"+name, func() (string, bool) { return c.resolver.ID(kind, name) })
+}
@@ -0,0 +1,217 @@
+//go:build !integration
+
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hpcugent/gpfsbeat/config"
+)
+
+// writeScript creates an executable shell script standing in for a command
+func writeScript(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const fakeGetent = `case "$1:$2" in
+passwd:1000|passwd:alice) echo "alice:x:1000:1000:Alice:/home/alice:/bin/bash" ;;
+group:100|group:users) echo "users:x:100:alice,bob" ;;
+*) exit 2 ;;
+esac
+`
+
+func TestNSSResolver(t *testing.T) {
+	r := &nssResolver{command: writeScript(t, t.TempDir(), "getent", fakeGetent)}
+
+	if name, ok := r.Name(User, "1000"); !ok || name != "alice" {
+		t.Errorf("expected alice, got %q (%t)", name, ok)
+	}
+	if id, ok := r.ID(User, "alice"); !ok || id != "1000" {
+		t.Errorf("expected 1000, got %q (%t)", id, ok)
+	}
+	if id, ok := r.ID(Group, "users"); !ok || id != "100" {
+		t.Errorf("expected 100, got %q (%t)", id, ok)
+	}
+	if _, ok := r.Name(User, "1001"); ok {
+		t.Error("an unknown user should not resolve")
+	}
+	if _, ok := r.Name(Group, "1000"); ok {
+		t.Error("a user ID should not resolve as a group")
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.csv")
+	content := "# kind,id,name\nUSR,2001,alice\nusr, 2002 , bob\nGRP,200,projects\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := newFileResolver(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name, ok := r.Name(User, "2002"); !ok || name != "bob" {
+		t.Errorf("expected bob, got %q (%t)", name, ok)
+	}
+	if id, ok := r.ID(Group, "projects"); !ok || id != "200" {
+		t.Errorf("expected 200, got %q (%t)", id, ok)
+	}
+	if _, ok := r.ID(Group, "alice"); ok {
+		t.Error("a user name should not resolve as a group")
+	}
+
+	if err := os.WriteFile(path, []byte("USR,2001\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newFileResolver(path); err == nil {
+		t.Error("expected an error for a line without a name")
+	}
+}
+
+func TestLDAPResolver(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "ldapsearch.log")
+	// logs the password (file) and the filter, and knows a single user and group
+	script := `log=` + log + `
+while [ $# -gt 2 ]; do
+	case "$1" in
+	-w) echo "password on the command line" >> $log ;;
+	-y) echo "file $2" >> $log; echo "password $(cat $2)" >> $log; shift ;;
+	esac
+	shift
+done
+printf "filter %s\n" "$1" >> $log
+case "$1" in
+"(&(objectClass=posixAccount)(uidNumber=1000))") printf 'dn: uid=alice,ou=people,dc=example\nuid: alice\n\n'; exit 4 ;;
+"(&(objectClass=posixGroup)(cn=users))") printf 'dn: cn=users,ou=groups,dc=example\ngidNumber: 100\n' ;;
+"(&(objectClass=posixAccount)(uidNumber=1001))") printf 'dn: uid=j\\C3\\B6rg,ou=people,dc=example\nuid:: asO2cmc=\n' ;;
+esac
+`
+	r := &ldapResolver{config: config.LDAPConfig{
+		URI:               "ldap://ldap.example",
+		BaseDN:            "dc=example",
+		BindDN:            "cn=gpfsbeat,dc=example",
+		BindPassword:      "s3cret",
+		LdapSearchCommand: writeScript(t, dir, "ldapsearch", script),
+	}}
+
+	if name, ok := r.Name(User, "1000"); !ok || name != "alice" {
+		t.Errorf("expected alice, got %q (%t)", name, ok)
+	}
+	if id, ok := r.ID(Group, "users"); !ok || id != "100" {
+		t.Errorf("expected 100, got %q (%t)", id, ok)
+	}
+	if name, ok := r.Name(User, "1001"); !ok || name != "jörg" {
+		t.Errorf("expected the base64 encoded name jörg, got %q (%t)", name, ok)
+	}
+	if _, ok := r.ID(User, "a*"); ok {
+		t.Error("an unknown user should not resolve")
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 12 {
+		t.Fatalf("expected 4 searches with a password file, got %q", lines)
+	}
+	for i := 0; i < len(lines); i += 3 {
+		passwordFile := strings.TrimPrefix(lines[i], "file ")
+		if _, err := os.Stat(passwordFile); !os.IsNotExist(err) {
+			t.Errorf("password file %s was not removed", passwordFile)
+		}
+		if lines[i+1] != "password s3cret" {
+			t.Errorf("expected the bind password in the file, got %q", lines[i+1])
+		}
+	}
+	if lines[11] != `filter (&(objectClass=posixAccount)(uid=a\2a))` {
+		t.Errorf("expected an escaped filter, got %q", lines[8])
+	}
+}
+
+// countingResolver knows every ID and counts how often it is asked
+type countingResolver struct {
+	calls int
+}
+
+func (r *countingResolver) Name(kind string, id string) (string, bool) {
+	r.calls++
+	return "name-" + id, id != "0"
+}
+
+func (r *countingResolver) ID(kind string, name string) (string, bool) {
+	r.calls++
+	return "", false
+}
+
+func TestCachingResolver(t *testing.T) {
+	counting := &countingResolver{}
+	r := newCachingResolver(counting, time.Minute)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if name, ok := r.Name(User, "1000"); !ok || name != "name-1000" {
+			t.Fatalf("expected name-1000, got %q (%t)", name, ok)
+		}
+		if _, ok := r.Name(User, "0"); ok {
+			t.Fatal("expected a negative result")
+		}
+	}
+	if counting.calls != 2 {
+		t.Errorf("expected the positive and negative results to be cached, got %d calls", counting.calls)
+	}
+	if _, ok := r.Name(Group, "1000"); !ok || counting.calls != 3 {
+		t.Errorf("expected a separate cache entry per kind, got %d calls", counting.calls)
+	}
+
+	now = now.Add(59 * time.Second)
+	r.Name(User, "1000")
+	if counting.calls != 3 {
+		t.Errorf("expected the entry to be cached until it expires, got %d calls", counting.calls)
+	}
+	now = now.Add(time.Second)
+	r.Name(User, "1000")
+	if counting.calls != 4 {
+		t.Errorf("expected the entry to expire, got %d calls", counting.calls)
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	mapping := filepath.Join(dir, "mapping.csv")
+	if err := os.WriteFile(mapping, []byte("USR,1000,override\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(config.ResolverConfig{
+		Sources:       []string{"file", "nss"},
+		TTL:           time.Minute,
+		GetentCommand: writeScript(t, dir, "getent", fakeGetent),
+		MappingFile:   mapping,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := r.Name(User, "1000"); name != "override" {
+		t.Errorf("expected the mapping file to go first, got %q", name)
+	}
+	if id, _ := r.ID(Group, "users"); id != "100" {
+		t.Errorf("expected to fall back to nss, got %q", id)
+	}
+
+	if _, err := New(config.ResolverConfig{Sources: []string{"nis"}}); err == nil {
+		t.Error("expected an error for an unknown source")
+	}
+}